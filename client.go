@@ -172,17 +172,18 @@ func newClient(sendConn sendConn, connIDGenerator ConnectionIDGenerator, config
 		return nil, err
 	}
 	c := &client{
-		connIDGenerator: connIDGenerator,
-		srcConnID:       srcConnID,
-		destConnID:      destConnID,
-		sendConn:        sendConn,
-		use0RTT:         use0RTT,
-		onClose:         onClose,
-		tlsConf:         tlsConf,
-		config:          config,
-		version:         config.Versions[0],
-		handshakeChan:   make(chan struct{}),
-		logger:          utils.DefaultLogger.WithPrefix("client"),
+		connIDGenerator:     connIDGenerator,
+		srcConnID:           srcConnID,
+		destConnID:          destConnID,
+		sendConn:            sendConn,
+		use0RTT:             use0RTT,
+		onClose:             onClose,
+		tlsConf:             tlsConf,
+		config:              config,
+		version:             config.Versions[0],
+		initialPacketNumber: protocol.InvalidPacketNumber, // start at a random packet number
+		handshakeChan:       make(chan struct{}),
+		logger:              utils.DefaultLogger.WithPrefix("client"),
 	}
 	return c, nil
 }
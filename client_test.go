@@ -311,6 +311,56 @@ var _ = Describe("Client", func() {
 			Expect(conf.Versions).To(Equal(config.Versions))
 		})
 
+		It("offers the supported versions by default", func() {
+			var version protocol.VersionNumber
+			var conf *Config
+			done := make(chan struct{})
+			newClientConnection = func(
+				_ sendConn,
+				_ connRunner,
+				_ protocol.ConnectionID,
+				_ protocol.ConnectionID,
+				_ ConnectionIDGenerator,
+				configP *Config,
+				_ *tls.Config,
+				_ protocol.PacketNumber,
+				_ bool,
+				_ bool,
+				_ *logging.ConnectionTracer,
+				_ uint64,
+				_ utils.Logger,
+				versionP protocol.VersionNumber,
+			) quicConn {
+				version = versionP
+				conf = configP
+				conn := NewMockQUICConn(mockCtrl)
+				conn.EXPECT().run()
+				conn.EXPECT().HandshakeComplete().Return(make(chan struct{}))
+				conn.EXPECT().destroy(gomock.Any()).MaxTimes(1)
+				close(done)
+				return conn
+			}
+			packetConn := NewMockPacketConn(mockCtrl)
+			packetConn.EXPECT().ReadFrom(gomock.Any()).DoAndReturn(func([]byte) (int, net.Addr, error) {
+				<-done
+				return 0, nil, errors.New("closed")
+			})
+			packetConn.EXPECT().LocalAddr()
+			packetConn.EXPECT().SetReadDeadline(gomock.Any()).AnyTimes()
+			_, err := Dial(context.Background(), packetConn, &net.UDPAddr{}, tlsConf, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(done).Should(BeClosed())
+			Expect(SupportedVersions()).ToNot(BeEmpty())
+			Expect(conf.Versions).To(Equal(SupportedVersions()))
+			Expect(version).To(Equal(SupportedVersions()[0]))
+		})
+
+		It("returns a copy of the supported versions", func() {
+			versions := SupportedVersions()
+			versions[0] = 0x1337
+			Expect(SupportedVersions()).To(Equal(protocol.SupportedVersions))
+		})
+
 		It("creates a new connections after version negotiation", func() {
 			var counter int
 			newClientConnection = func(
@@ -332,7 +382,8 @@ var _ = Describe("Client", func() {
 				conn := NewMockQUICConn(mockCtrl)
 				conn.EXPECT().HandshakeComplete().Return(make(chan struct{}))
 				if counter == 0 {
-					Expect(pn).To(BeZero())
+					// the first packet number is chosen randomly by the ackhandler
+					Expect(pn).To(Equal(protocol.InvalidPacketNumber))
 					Expect(hasNegotiatedVersion).To(BeFalse())
 					conn.EXPECT().run().DoAndReturn(func() error {
 						runner.Remove(connID)
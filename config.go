@@ -19,6 +19,12 @@ func (c *Config) handshakeTimeout() time.Duration {
 	return 2 * c.HandshakeIdleTimeout
 }
 
+// handshakeNoProgressTimeout is the time after which the connection attempt is aborted
+// if packets are received, but the handshake doesn't make any progress.
+func (c *Config) handshakeNoProgressTimeout() time.Duration {
+	return 3 * c.HandshakeIdleTimeout / 2
+}
+
 func (c *Config) maxRetryTokenAge() time.Duration {
 	return c.handshakeTimeout()
 }
@@ -40,6 +46,12 @@ func validateConfig(config *Config) error {
 	if config.MaxConnectionReceiveWindow > quicvarint.Max {
 		config.MaxConnectionReceiveWindow = quicvarint.Max
 	}
+	if config.MaxGSOBatchSize > protocol.MaxGSOSegments {
+		config.MaxGSOBatchSize = protocol.MaxGSOSegments
+	}
+	if config.MaxAckRanges > protocol.MaxNumAckRanges {
+		config.MaxAckRanges = protocol.MaxNumAckRanges
+	}
 	// check that all QUIC versions are actually supported
 	for _, v := range config.Versions {
 		if !protocol.IsValidVersion(v) {
@@ -105,6 +117,18 @@ func populateConfig(config *Config) *Config {
 	} else if maxIncomingUniStreams < 0 {
 		maxIncomingUniStreams = 0
 	}
+	maxGSOBatchSize := config.MaxGSOBatchSize
+	if maxGSOBatchSize <= 0 {
+		maxGSOBatchSize = protocol.MaxGSOSegments
+	}
+	ackElicitingThreshold := config.AckElicitingThreshold
+	if ackElicitingThreshold <= 0 {
+		ackElicitingThreshold = protocol.DefaultAckElicitingThreshold
+	}
+	maxAckRanges := config.MaxAckRanges
+	if maxAckRanges <= 0 {
+		maxAckRanges = protocol.MaxNumAckRanges
+	}
 
 	return &Config{
 		GetConfigForClient:             config.GetConfigForClient,
@@ -113,17 +137,24 @@ func populateConfig(config *Config) *Config {
 		MaxIdleTimeout:                 idleTimeout,
 		RequireAddressValidation:       config.RequireAddressValidation,
 		KeepAlivePeriod:                config.KeepAlivePeriod,
+		MaxConnectionDuration:          config.MaxConnectionDuration,
 		InitialStreamReceiveWindow:     initialStreamReceiveWindow,
 		MaxStreamReceiveWindow:         maxStreamReceiveWindow,
 		InitialConnectionReceiveWindow: initialConnectionReceiveWindow,
 		MaxConnectionReceiveWindow:     maxConnectionReceiveWindow,
 		AllowConnectionWindowIncrease:  config.AllowConnectionWindowIncrease,
+		MaxSendBufferSize:              config.MaxSendBufferSize,
 		MaxIncomingStreams:             maxIncomingStreams,
 		MaxIncomingUniStreams:          maxIncomingUniStreams,
 		TokenStore:                     config.TokenStore,
 		EnableDatagrams:                config.EnableDatagrams,
 		DisablePathMTUDiscovery:        config.DisablePathMTUDiscovery,
+		DisableSpinBit:                 config.DisableSpinBit,
+		MaxGSOBatchSize:                maxGSOBatchSize,
+		AckElicitingThreshold:          ackElicitingThreshold,
+		MaxAckRanges:                   maxAckRanges,
 		Allow0RTT:                      config.Allow0RTT,
 		Tracer:                         config.Tracer,
+		OnConnectionError:              config.OnConnectionError,
 	}
 }
@@ -51,6 +51,18 @@ var _ = Describe("Config", func() {
 			Expect(conf.MaxStreamReceiveWindow).To(BeEquivalentTo(uint64(quicvarint.Max)))
 			Expect(conf.MaxConnectionReceiveWindow).To(BeEquivalentTo(uint64(quicvarint.Max)))
 		})
+
+		It("clips too large values for the GSO batch size", func() {
+			conf := &Config{MaxGSOBatchSize: protocol.MaxGSOSegments + 1}
+			Expect(validateConfig(conf)).To(Succeed())
+			Expect(conf.MaxGSOBatchSize).To(Equal(protocol.MaxGSOSegments))
+		})
+
+		It("clips too large values for the maximum number of ACK ranges", func() {
+			conf := &Config{MaxAckRanges: protocol.MaxNumAckRanges + 1}
+			Expect(validateConfig(conf)).To(Succeed())
+			Expect(conf.MaxAckRanges).To(Equal(protocol.MaxNumAckRanges))
+		})
 	})
 
 	configWithNonZeroNonFunctionFields := func() *Config {
@@ -66,7 +78,7 @@ var _ = Describe("Config", func() {
 			}
 
 			switch fn := typ.Field(i).Name; fn {
-			case "GetConfigForClient", "RequireAddressValidation", "GetLogWriter", "AllowConnectionWindowIncrease", "Tracer":
+			case "GetConfigForClient", "RequireAddressValidation", "GetLogWriter", "AllowConnectionWindowIncrease", "Tracer", "OnConnectionError":
 				// Can't compare functions.
 			case "Versions":
 				f.Set(reflect.ValueOf([]VersionNumber{1, 2, 3}))
@@ -96,12 +108,24 @@ var _ = Describe("Config", func() {
 				f.Set(reflect.ValueOf(&StatelessResetKey{1, 2, 3, 4}))
 			case "KeepAlivePeriod":
 				f.Set(reflect.ValueOf(time.Second))
+			case "MaxConnectionDuration":
+				f.Set(reflect.ValueOf(time.Minute))
 			case "EnableDatagrams":
 				f.Set(reflect.ValueOf(true))
 			case "DisableVersionNegotiationPackets":
 				f.Set(reflect.ValueOf(true))
 			case "DisablePathMTUDiscovery":
 				f.Set(reflect.ValueOf(true))
+			case "DisableSpinBit":
+				f.Set(reflect.ValueOf(true))
+			case "MaxGSOBatchSize":
+				f.Set(reflect.ValueOf(13))
+			case "AckElicitingThreshold":
+				f.Set(reflect.ValueOf(15))
+			case "MaxAckRanges":
+				f.Set(reflect.ValueOf(16))
+			case "MaxSendBufferSize":
+				f.Set(reflect.ValueOf(uint64(14)))
 			case "Allow0RTT":
 				f.Set(reflect.ValueOf(true))
 			default:
@@ -116,9 +140,14 @@ var _ = Describe("Config", func() {
 		Expect(c.handshakeTimeout()).To(Equal(11 * time.Second))
 	})
 
+	It("uses 1.5 times the handshake idle timeout for the handshake no-progress timeout", func() {
+		c := &Config{HandshakeIdleTimeout: 4 * time.Second}
+		Expect(c.handshakeNoProgressTimeout()).To(Equal(6 * time.Second))
+	})
+
 	Context("cloning", func() {
 		It("clones function fields", func() {
-			var calledAddrValidation, calledAllowConnectionWindowIncrease, calledTracer bool
+			var calledAddrValidation, calledAllowConnectionWindowIncrease, calledTracer, calledOnConnectionError bool
 			c1 := &Config{
 				GetConfigForClient:            func(info *ClientHelloInfo) (*Config, error) { return nil, errors.New("nope") },
 				AllowConnectionWindowIncrease: func(Connection, uint64) bool { calledAllowConnectionWindowIncrease = true; return true },
@@ -127,6 +156,7 @@ var _ = Describe("Config", func() {
 					calledTracer = true
 					return nil
 				},
+				OnConnectionError: func(error) { calledOnConnectionError = true },
 			}
 			c2 := c1.Clone()
 			c2.RequireAddressValidation(&net.UDPAddr{})
@@ -137,6 +167,8 @@ var _ = Describe("Config", func() {
 			Expect(err).To(MatchError("nope"))
 			c2.Tracer(context.Background(), logging.PerspectiveClient, protocol.ConnectionID{})
 			Expect(calledTracer).To(BeTrue())
+			c2.OnConnectionError(errors.New("foobar"))
+			Expect(calledOnConnectionError).To(BeTrue())
 		})
 
 		It("clones non-function fields", func() {
@@ -184,6 +216,10 @@ var _ = Describe("Config", func() {
 			Expect(c.MaxIncomingStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingStreams))
 			Expect(c.MaxIncomingUniStreams).To(BeEquivalentTo(protocol.DefaultMaxIncomingUniStreams))
 			Expect(c.DisablePathMTUDiscovery).To(BeFalse())
+			Expect(c.DisableSpinBit).To(BeFalse())
+			Expect(c.MaxGSOBatchSize).To(Equal(protocol.MaxGSOSegments))
+			Expect(c.AckElicitingThreshold).To(Equal(protocol.DefaultAckElicitingThreshold))
+			Expect(c.MaxAckRanges).To(Equal(protocol.MaxNumAckRanges))
 			Expect(c.GetConfigForClient).To(BeNil())
 		})
 
@@ -3,6 +3,7 @@ package quic
 import (
 	"fmt"
 
+	"github.com/quic-go/quic-go/internal/ackhandler"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
 	"github.com/quic-go/quic-go/internal/utils"
@@ -24,6 +25,8 @@ type connIDManager struct {
 	highestRetired            uint64
 	activeConnectionID        protocol.ConnectionID
 	activeStatelessResetToken *protocol.StatelessResetToken
+	// the number of RETIRE_CONNECTION_ID frames that haven't been acknowledged yet
+	numUnackedRetirements int
 
 	// We change the connection ID after sending on average
 	// protocol.PacketsPerConnectionID packets. The actual value is randomized
@@ -62,6 +65,12 @@ func (h *connIDManager) Add(f *wire.NewConnectionIDFrame) error {
 	if h.queue.Len() >= protocol.MaxActiveConnectionIDs {
 		return &qerr.TransportError{ErrorCode: qerr.ConnectionIDLimitError}
 	}
+	if h.numUnackedRetirements > protocol.MaxRetiredConnectionIDs {
+		return &qerr.TransportError{
+			ErrorCode:    qerr.ConnectionIDLimitError,
+			ErrorMessage: "too many unacknowledged RETIRE_CONNECTION_ID frames",
+		}
+	}
 	return nil
 }
 
@@ -69,9 +78,7 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 	// If the NEW_CONNECTION_ID frame is reordered, such that its sequence number is smaller than the currently active
 	// connection ID or if it was already retired, send the RETIRE_CONNECTION_ID frame immediately.
 	if f.SequenceNumber < h.activeSequenceNumber || f.SequenceNumber < h.highestRetired {
-		h.queueControlFrame(&wire.RetireConnectionIDFrame{
-			SequenceNumber: f.SequenceNumber,
-		})
+		h.retire(f.SequenceNumber)
 		return nil
 	}
 
@@ -84,9 +91,7 @@ func (h *connIDManager) add(f *wire.NewConnectionIDFrame) error {
 				break
 			}
 			next = el.Next()
-			h.queueControlFrame(&wire.RetireConnectionIDFrame{
-				SequenceNumber: el.Value.SequenceNumber,
-			})
+			h.retire(el.Value.SequenceNumber)
 			h.queue.Remove(el)
 		}
 		h.highestRetired = f.RetirePriorTo
@@ -141,10 +146,13 @@ func (h *connIDManager) addConnectionID(seq uint64, connID protocol.ConnectionID
 	return nil
 }
 
+func (h *connIDManager) retire(seq uint64) {
+	h.numUnackedRetirements++
+	h.queueControlFrame(&wire.RetireConnectionIDFrame{SequenceNumber: seq})
+}
+
 func (h *connIDManager) updateConnectionID() {
-	h.queueControlFrame(&wire.RetireConnectionIDFrame{
-		SequenceNumber: h.activeSequenceNumber,
-	})
+	h.retire(h.activeSequenceNumber)
 	h.highestRetired = max(h.highestRetired, h.activeSequenceNumber)
 	if h.activeStatelessResetToken != nil {
 		h.removeStatelessResetToken(*h.activeStatelessResetToken)
@@ -209,6 +217,38 @@ func (h *connIDManager) Get() protocol.ConnectionID {
 	return h.activeConnectionID
 }
 
+// GetUnused returns a connection ID that hasn't been used yet, e.g. for probing a new path.
+// The connection ID is removed from the queue. It needs to be retired using Retire once it's not used any more.
+func (h *connIDManager) GetUnused() (uint64, protocol.ConnectionID, bool) {
+	if h.queue.Len() == 0 {
+		return 0, protocol.ConnectionID{}, false
+	}
+	c := h.queue.Remove(h.queue.Front())
+	return c.SequenceNumber, c.ConnectionID, true
+}
+
+// Retire retires a connection ID returned by GetUnused.
+func (h *connIDManager) Retire(seq uint64) {
+	h.retire(seq)
+	// Make sure that a retransmission of the NEW_CONNECTION_ID frame doesn't add the connection ID again.
+	h.highestRetired = max(h.highestRetired, seq+1)
+}
+
 func (h *connIDManager) SetHandshakeComplete() {
 	h.handshakeComplete = true
 }
+
+// RetirementAckHandler returns the ackhandler.FrameHandler for RETIRE_CONNECTION_ID frames.
+func (h *connIDManager) RetirementAckHandler() ackhandler.FrameHandler {
+	return (*connIDManagerRetirementAckHandler)(h)
+}
+
+type connIDManagerRetirementAckHandler connIDManager
+
+func (h *connIDManagerRetirementAckHandler) OnAcked(wire.Frame) {
+	h.numUnackedRetirements--
+}
+
+func (h *connIDManagerRetirementAckHandler) OnLost(f wire.Frame) {
+	h.queueControlFrame(f)
+}
@@ -160,6 +160,40 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{3, 4, 5, 6})))
 	})
 
+	It("retires reordered connection IDs when a frame with a higher retire_prior_to arrives", func() {
+		for _, s := range []uint8{3, 1, 2} {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber:      uint64(s),
+				ConnectionID:        protocol.ParseConnectionID([]byte{s, s, s, s}),
+				StatelessResetToken: protocol.StatelessResetToken{s, s, s, s, s, s, s, s, s, s, s, s, s, s, s, s},
+			})).To(Succeed())
+		}
+		Expect(frameQueue).To(BeEmpty())
+		Expect(m.queue.Front().Value.ConnectionID).To(Equal(protocol.ParseConnectionID([]byte{1, 1, 1, 1})))
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber:      5,
+			RetirePriorTo:       3,
+			ConnectionID:        protocol.ParseConnectionID([]byte{5, 5, 5, 5}),
+			StatelessResetToken: protocol.StatelessResetToken{5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5},
+		})).To(Succeed())
+		Expect(frameQueue).To(HaveLen(3))
+		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(1))
+		Expect(frameQueue[1].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(2))
+		Expect(frameQueue[2].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeZero())
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{3, 3, 3, 3})))
+		Expect(m.queue.Len()).To(Equal(1))
+		Expect(m.queue.Front().Value.ConnectionID).To(Equal(protocol.ParseConnectionID([]byte{5, 5, 5, 5})))
+		// a delayed frame for a sequence number that was already retired is retired right away
+		frameQueue = nil
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 2,
+			ConnectionID:   protocol.ParseConnectionID([]byte{2, 2, 2, 2}),
+		})).To(Succeed())
+		Expect(frameQueue).To(HaveLen(1))
+		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(2))
+		Expect(m.queue.Len()).To(Equal(1))
+	})
+
 	It("ignores reordered connection IDs, if their sequence number was already retired", func() {
 		Expect(m.Add(&wire.NewConnectionIDFrame{
 			SequenceNumber: 10,
@@ -199,6 +233,56 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(frameQueue[0].(*wire.RetireConnectionIDFrame).SequenceNumber).To(BeEquivalentTo(9))
 	})
 
+	It("requeues lost RETIRE_CONNECTION_ID frames", func() {
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 10,
+			ConnectionID:   protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+			RetirePriorTo:  10,
+		})).To(Succeed())
+		Expect(frameQueue).To(HaveLen(1))
+		f := frameQueue[0]
+		frameQueue = nil
+		m.RetirementAckHandler().OnLost(f)
+		Expect(frameQueue).To(Equal([]wire.Frame{f}))
+		Expect(m.numUnackedRetirements).To(Equal(1))
+		m.RetirementAckHandler().OnAcked(f)
+		Expect(m.numUnackedRetirements).To(BeZero())
+	})
+
+	It("errors when the peer makes us retire too many connection IDs without acknowledging the RETIRE_CONNECTION_ID frames", func() {
+		var err error
+		for i := uint64(1); i <= 100; i++ {
+			err = m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber: i,
+				ConnectionID:   protocol.ParseConnectionID([]byte{byte(i), byte(i), byte(i), byte(i)}),
+				RetirePriorTo:  i,
+			})
+			if err != nil {
+				break
+			}
+		}
+		Expect(err).To(MatchError(&qerr.TransportError{
+			ErrorCode:    qerr.ConnectionIDLimitError,
+			ErrorMessage: "too many unacknowledged RETIRE_CONNECTION_ID frames",
+		}))
+		Expect(frameQueue).To(HaveLen(protocol.MaxRetiredConnectionIDs + 1))
+	})
+
+	It("doesn't error when the RETIRE_CONNECTION_ID frames are acknowledged", func() {
+		for i := uint64(1); i <= 100; i++ {
+			Expect(m.Add(&wire.NewConnectionIDFrame{
+				SequenceNumber: i,
+				ConnectionID:   protocol.ParseConnectionID([]byte{byte(i), byte(i), byte(i), byte(i)}),
+				RetirePriorTo:  i,
+			})).To(Succeed())
+			for _, f := range frameQueue {
+				m.RetirementAckHandler().OnAcked(f)
+			}
+			frameQueue = nil
+		}
+		Expect(m.Get()).To(Equal(protocol.ParseConnectionID([]byte{100, 100, 100, 100})))
+	})
+
 	It("accepts retransmissions for the connection ID that is in use", func() {
 		connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4})
 
@@ -283,6 +367,10 @@ var _ = Describe("Connection ID Manager", func() {
 				lastConnID = connID
 				Expect(removedTokens).To(HaveLen(1))
 				removedTokens = nil
+				for _, f := range frameQueue {
+					m.RetirementAckHandler().OnAcked(f)
+				}
+				frameQueue = nil
 				Expect(m.Add(&wire.NewConnectionIDFrame{
 					SequenceNumber:      uint64(s),
 					ConnectionID:        protocol.ParseConnectionID([]byte{s, s, s, s}),
@@ -361,4 +449,33 @@ var _ = Describe("Connection ID Manager", func() {
 		Expect(removedTokens).To(HaveLen(1))
 		Expect(removedTokens[0]).To(Equal(protocol.StatelessResetToken{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}))
 	})
+
+	It("hands out unused connection IDs", func() {
+		_, _, ok := m.GetUnused()
+		Expect(ok).To(BeFalse())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 1,
+			ConnectionID:   protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+		})).To(Succeed())
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 2,
+			ConnectionID:   protocol.ParseConnectionID([]byte{2, 3, 4, 5}),
+		})).To(Succeed())
+		seq, connID, ok := m.GetUnused()
+		Expect(ok).To(BeTrue())
+		Expect(seq).To(BeEquivalentTo(1))
+		Expect(connID).To(Equal(protocol.ParseConnectionID([]byte{1, 2, 3, 4})))
+		Expect(m.Get()).To(Equal(initialConnID))
+		m.Retire(seq)
+		Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 1}}))
+		// a retransmission of the NEW_CONNECTION_ID frame doesn't add the connection ID again
+		frameQueue = nil
+		Expect(m.Add(&wire.NewConnectionIDFrame{
+			SequenceNumber: 1,
+			ConnectionID:   protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
+		})).To(Succeed())
+		Expect(frameQueue).To(Equal([]wire.Frame{&wire.RetireConnectionIDFrame{SequenceNumber: 1}}))
+		Expect(m.queue.Len()).To(Equal(1))
+		Expect(m.queue.Front().Value.SequenceNumber).To(BeEquivalentTo(2))
+	})
 })
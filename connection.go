@@ -3,6 +3,7 @@ package quic
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"crypto/tls"
 	"errors"
 	"fmt"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/quic-go/quic-go/internal/ackhandler"
+	"github.com/quic-go/quic-go/internal/congestion"
 	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/handshake"
 	"github.com/quic-go/quic-go/internal/logutils"
@@ -183,10 +185,15 @@ type connection struct {
 	receivedRetry       bool
 	versionNegotiated   bool
 	receivedFirstPacket bool
+	// the largest packet number of all 1-RTT packets received, used for the spin bit
+	largestRcvdShortHeaderPN protocol.PacketNumber
+	disableSpinBit           bool
 
 	// the minimum of the max_idle_timeout values advertised by both endpoints
 	idleTimeout  time.Duration
 	creationTime time.Time
+	// the time when we last processed a new handshake message, used to detect handshakes that are stuck
+	lastHandshakeProgressTime time.Time
 	// The idle timeout is set based on the max of the time we received the last packet...
 	lastPacketReceivedTime time.Time
 	// ... and the time we sent a new ack-eliciting packet after receiving a packet.
@@ -204,9 +211,25 @@ type connection struct {
 
 	datagramQueue *datagramQueue
 
+	pathProbeMutex   sync.Mutex
+	queuedPathProbes []*pathProbe // path probes that the run loop still needs to send
+	// outstanding path probes, indexed by the data of the PATH_CHALLENGE frame
+	// It is nil until the first PATH_CHALLENGE is sent, and only accessed by the run loop.
+	pathProbes map[[8]byte]*pathProbe
+	// data of path probes that finished recently, and the time until which a PATH_RESPONSE is still accepted for them
+	// Only accessed by the run loop.
+	finishedPathProbes map[[8]byte]time.Time
+
 	connStateMutex sync.Mutex
 	connState      ConnectionState
 
+	// the packet size currently used, as determined by DPLPMTUD
+	// It is accessed from the run loop as well as by the application.
+	currentMaxPacketSize atomic.Int64
+	// the time (in Unix nanoseconds) when the last packet was sent or received
+	// It is accessed from the run loop as well as by the application.
+	lastActivity atomic.Int64
+
 	logID  string
 	tracer *logging.ConnectionTracer
 	logger utils.Logger
@@ -274,16 +297,19 @@ var newConnection = func(
 	s.preSetup()
 	s.ctx, s.ctxCancel = context.WithCancelCause(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
-		0,
+		protocol.InvalidPacketNumber, // start at a random packet number
+		nil,
 		getMaxPacketSize(s.conn.RemoteAddr()),
 		s.rttStats,
 		clientAddressValidated,
 		s.conn.capabilities().ECN,
+		s.config.AckElicitingThreshold,
 		s.perspective,
 		s.tracer,
 		s.logger,
 	)
-	s.mtuDiscoverer = newMTUDiscoverer(s.rttStats, getMaxPacketSize(s.conn.RemoteAddr()), s.sentPacketHandler.SetMaxDatagramSize)
+	s.currentMaxPacketSize.Store(int64(getMaxPacketSize(s.conn.RemoteAddr())))
+	s.mtuDiscoverer = newMTUDiscoverer(s.rttStats, getMaxPacketSize(s.conn.RemoteAddr()), s.onMTUIncreased)
 	params := &wire.TransportParameters{
 		InitialMaxStreamDataBidiLocal:   protocol.ByteCount(s.config.InitialStreamReceiveWindow),
 		InitialMaxStreamDataBidiRemote:  protocol.ByteCount(s.config.InitialStreamReceiveWindow),
@@ -327,7 +353,7 @@ var newConnection = func(
 		s.version,
 	)
 	s.cryptoStreamHandler = cs
-	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, cs, s.framer, s.receivedPacketHandler, s.datagramQueue, s.perspective)
+	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, s.connIDManager.RetirementAckHandler(), cs, s.framer, s.receivedPacketHandler, s.datagramQueue, s.perspective, s.disableSpinBit, s.config.MaxAckRanges, s.tracer != nil && s.tracer.SentRawFrame != nil)
 	s.unpacker = newPacketUnpacker(cs, s.srcConnIDLen)
 	s.cryptoStreamManager = newCryptoStreamManager(cs, s.initialStream, s.handshakeStream, s.oneRTTStream)
 	return s
@@ -384,15 +410,18 @@ var newClientConnection = func(
 	s.ctx, s.ctxCancel = context.WithCancelCause(context.WithValue(context.Background(), ConnectionTracingKey, tracingID))
 	s.sentPacketHandler, s.receivedPacketHandler = ackhandler.NewAckHandler(
 		initialPacketNumber,
+		nil,
 		getMaxPacketSize(s.conn.RemoteAddr()),
 		s.rttStats,
 		false, // has no effect
 		s.conn.capabilities().ECN,
+		s.config.AckElicitingThreshold,
 		s.perspective,
 		s.tracer,
 		s.logger,
 	)
-	s.mtuDiscoverer = newMTUDiscoverer(s.rttStats, getMaxPacketSize(s.conn.RemoteAddr()), s.sentPacketHandler.SetMaxDatagramSize)
+	s.currentMaxPacketSize.Store(int64(getMaxPacketSize(s.conn.RemoteAddr())))
+	s.mtuDiscoverer = newMTUDiscoverer(s.rttStats, getMaxPacketSize(s.conn.RemoteAddr()), s.onMTUIncreased)
 	oneRTTStream := newCryptoStream()
 	params := &wire.TransportParameters{
 		InitialMaxStreamDataBidiRemote: protocol.ByteCount(s.config.InitialStreamReceiveWindow),
@@ -434,7 +463,7 @@ var newClientConnection = func(
 	s.cryptoStreamHandler = cs
 	s.cryptoStreamManager = newCryptoStreamManager(cs, s.initialStream, s.handshakeStream, oneRTTStream)
 	s.unpacker = newPacketUnpacker(cs, s.srcConnIDLen)
-	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, cs, s.framer, s.receivedPacketHandler, s.datagramQueue, s.perspective)
+	s.packer = newPacketPacker(srcConnID, s.connIDManager.Get, s.initialStream, s.handshakeStream, s.sentPacketHandler, s.retransmissionQueue, s.connIDManager.RetirementAckHandler(), cs, s.framer, s.receivedPacketHandler, s.datagramQueue, s.perspective, s.disableSpinBit, s.config.MaxAckRanges, s.tracer != nil && s.tracer.SentRawFrame != nil)
 	if len(tlsConf.ServerName) > 0 {
 		s.tokenStoreKey = tlsConf.ServerName
 	} else {
@@ -469,9 +498,14 @@ func (s *connection) preSetup() {
 		s.logger,
 	)
 	s.earlyConnReadyChan = make(chan struct{})
+	var sendBuffer *sendBufferLimiter
+	if s.config.MaxSendBufferSize > 0 {
+		sendBuffer = newSendBufferLimiter(protocol.ByteCount(s.config.MaxSendBufferSize), s.onHasStreamData)
+	}
 	s.streamsMap = newStreamsMap(
 		s,
 		s.newFlowController,
+		sendBuffer,
 		uint64(s.config.MaxIncomingStreams),
 		uint64(s.config.MaxIncomingUniStreams),
 		s.perspective,
@@ -485,6 +519,9 @@ func (s *connection) preSetup() {
 	now := time.Now()
 	s.lastPacketReceivedTime = now
 	s.creationTime = now
+	s.lastHandshakeProgressTime = now
+	s.largestRcvdShortHeaderPN = protocol.InvalidPacketNumber
+	s.disableSpinBit = s.config.DisableSpinBit || randomlyDisableSpinBit()
 
 	s.windowUpdateQueue = newWindowUpdateQueue(s.streamsMap, s.connFlowController, s.framer.QueueControlFrame)
 	s.datagramQueue = newDatagramQueue(s.scheduleSending, s.logger)
@@ -601,28 +638,21 @@ runLoop:
 		if timeout := s.sentPacketHandler.GetLossDetectionTimeout(); !timeout.IsZero() && timeout.Before(now) {
 			// This could cause packets to be retransmitted.
 			// Check it before trying to send packets.
-			if err := s.sentPacketHandler.OnLossDetectionTimeout(); err != nil {
+			if err := s.sentPacketHandler.OnLossDetectionTimeout(now); err != nil {
 				s.closeLocal(err)
 			}
 		}
 
-		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
-			// send a PING frame since there is no activity in the connection
-			s.logger.Debugf("Sending a keep-alive PING to keep the connection alive.")
-			s.framer.QueueControlFrame(&wire.PingFrame{})
-			s.keepAlivePingSent = true
-		} else if !s.handshakeComplete && now.Sub(s.creationTime) >= s.config.handshakeTimeout() {
-			s.destroyImpl(qerr.ErrHandshakeTimeout)
+		if destroyed := s.handleTimeouts(now); destroyed {
 			continue
-		} else {
-			idleTimeoutStartTime := s.idleTimeoutStartTime()
-			if (!s.handshakeComplete && now.Sub(idleTimeoutStartTime) >= s.config.HandshakeIdleTimeout) ||
-				(s.handshakeComplete && now.After(s.nextIdleTimeoutTime())) {
-				s.destroyImpl(qerr.ErrIdleTimeout)
-				continue
-			}
 		}
 
+		if deadline := s.maxConnectionDurationDeadline(); !deadline.IsZero() && !now.Before(deadline) {
+			s.closeLocal(qerr.ErrMaxConnectionDuration)
+		}
+
+		s.handlePathProbes(now)
+
 		if s.sendQueue.WouldBlock() {
 			// The send queue is still busy sending out packets.
 			// Wait until there's space to enqueue new packets.
@@ -630,7 +660,12 @@ runLoop:
 			continue
 		}
 		if err := s.triggerSending(now); err != nil {
-			s.closeLocal(err)
+			if err == errPacketNumberSpaceExhausted {
+				// We can't send a CONNECTION_CLOSE frame, since this would require another packet number.
+				s.destroyImpl(err)
+			} else {
+				s.closeLocal(err)
+			}
 		}
 		if s.sendQueue.WouldBlock() {
 			sendQueueAvailable = s.sendQueue.Available()
@@ -675,10 +710,36 @@ func (s *connection) ConnectionState() ConnectionState {
 	cs := s.cryptoStreamHandler.ConnectionState()
 	s.connState.TLS = cs.ConnectionState
 	s.connState.Used0RTT = cs.Used0RTT
+	s.connState.KeyExchangeGroup = cs.KeyExchangeGroup
+	s.connState.SignatureScheme = cs.SignatureScheme
 	s.connState.GSO = s.conn.capabilities().GSO
 	return s.connState
 }
 
+func (s *connection) MaxPacketSize() int {
+	return int(s.currentMaxPacketSize.Load())
+}
+
+func (s *connection) LastActivity() time.Time {
+	t := s.lastActivity.Load()
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, t)
+}
+
+func (s *connection) onMTUIncreased(size protocol.ByteCount) {
+	s.currentMaxPacketSize.Store(int64(size))
+	s.sentPacketHandler.SetMaxDatagramSize(size)
+}
+
+// setCongestionController replaces the congestion controller of this connection.
+// This is useful for experimenting with different congestion control algorithms.
+// It must only be called from the run loop.
+func (s *connection) setCongestionController(cc congestion.SendAlgorithmWithDebugInfos) {
+	s.sentPacketHandler.SetCongestionController(cc)
+}
+
 // Time when the connection should time out
 func (s *connection) nextIdleTimeoutTime() time.Time {
 	idleTimeout := max(s.idleTimeout, s.rttStats.PTO(true)*3)
@@ -702,6 +763,9 @@ func (s *connection) maybeResetTimer() {
 			s.creationTime.Add(s.config.handshakeTimeout()),
 			s.idleTimeoutStartTime().Add(s.config.HandshakeIdleTimeout),
 		)
+		if progressDeadline := s.handshakeProgressDeadline(); !progressDeadline.IsZero() {
+			deadline = utils.MinTime(deadline, progressDeadline)
+		}
 	} else {
 		if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() {
 			deadline = keepAliveTime
@@ -709,6 +773,12 @@ func (s *connection) maybeResetTimer() {
 			deadline = s.nextIdleTimeoutTime()
 		}
 	}
+	if maxDurationDeadline := s.maxConnectionDurationDeadline(); !maxDurationDeadline.IsZero() {
+		deadline = utils.MinTime(deadline, maxDurationDeadline)
+	}
+	if probeDeadline := s.pathProbeDeadline(); !probeDeadline.IsZero() {
+		deadline = utils.MinTime(deadline, probeDeadline)
+	}
 
 	s.timer.SetTimer(
 		deadline,
@@ -718,6 +788,52 @@ func (s *connection) maybeResetTimer() {
 	)
 }
 
+// handleTimeouts sends a keep-alive PING, or closes the connection if one of the timeouts expired.
+// It returns true if the connection was destroyed.
+func (s *connection) handleTimeouts(now time.Time) (destroyed bool) {
+	if keepAliveTime := s.nextKeepAliveTime(); !keepAliveTime.IsZero() && !now.Before(keepAliveTime) {
+		// send a PING frame since there is no activity in the connection
+		s.logger.Debugf("Sending a keep-alive PING to keep the connection alive.")
+		s.framer.QueueControlFrame(&wire.PingFrame{})
+		s.keepAlivePingSent = true
+		return false
+	}
+	if !s.handshakeComplete && now.Sub(s.creationTime) >= s.config.handshakeTimeout() {
+		s.destroyImpl(qerr.ErrHandshakeTimeout)
+		return true
+	}
+	idleTimeoutStartTime := s.idleTimeoutStartTime()
+	if (!s.handshakeComplete && now.Sub(idleTimeoutStartTime) >= s.config.HandshakeIdleTimeout) ||
+		(s.handshakeComplete && now.After(s.nextIdleTimeoutTime())) {
+		s.destroyImpl(qerr.ErrIdleTimeout)
+		return true
+	}
+	if deadline := s.handshakeProgressDeadline(); !deadline.IsZero() && !now.Before(deadline) {
+		s.destroyImpl(qerr.ErrHandshakeNoProgress)
+		return true
+	}
+	return false
+}
+
+// maxConnectionDurationDeadline returns the time when the connection is closed due to Config.MaxConnectionDuration.
+// It returns the zero value if the connection lifetime is not limited.
+func (s *connection) maxConnectionDurationDeadline() time.Time {
+	if s.config.MaxConnectionDuration <= 0 {
+		return time.Time{}
+	}
+	return s.creationTime.Add(s.config.MaxConnectionDuration)
+}
+
+// handshakeProgressDeadline returns the time when the connection is closed because the handshake is stuck:
+// The peer is still sending packets, but none of them advanced the handshake.
+// It returns the zero value if the handshake is complete, or if no packets were received since the handshake last made progress.
+func (s *connection) handshakeProgressDeadline() time.Time {
+	if s.handshakeComplete || !s.lastPacketReceivedTime.After(s.lastHandshakeProgressTime) {
+		return time.Time{}
+	}
+	return s.lastHandshakeProgressTime.Add(s.config.handshakeNoProgressTimeout())
+}
+
 func (s *connection) idleTimeoutStartTime() time.Time {
 	return utils.MaxTime(s.lastPacketReceivedTime, s.firstAckElicitingPacketAfterIdleSentTime)
 }
@@ -923,9 +1039,39 @@ func (s *connection) handleShortHeaderPacket(p receivedPacket, destConnID protoc
 		s.closeLocal(err)
 		return false
 	}
+	if pn > s.largestRcvdShortHeaderPN {
+		s.largestRcvdShortHeaderPN = pn
+		if !s.disableSpinBit {
+			// The spin bit is not covered by header protection.
+			s.updateSpinBit(p.data[0]&0x20 > 0)
+		}
+	}
 	return true
 }
 
+// randomlyDisableSpinBit decides if the spin bit is disabled for a connection that has the spin bit enabled.
+// RFC 9000, section 17.4: Even when the spin bit is not disabled by the administrator,
+// endpoints MUST disable their use of the spin bit for a random selection of at least
+// one in every 16 network paths, or for one in every 16 connection IDs.
+func randomlyDisableSpinBit() bool {
+	var b [1]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return true
+	}
+	return b[0]%16 == 0
+}
+
+// updateSpinBit updates the spin bit after receiving a 1-RTT packet with a larger packet number,
+// as described in RFC 9000, section 17.4:
+// The server sets the spin bit to the value received, the client sets it to the inverse of that value.
+func (s *connection) updateSpinBit(rcvdSpinBit bool) {
+	if s.perspective == protocol.PerspectiveServer {
+		s.packer.SetSpinBit(rcvdSpinBit)
+	} else {
+		s.packer.SetSpinBit(!rcvdSpinBit)
+	}
+}
+
 func (s *connection) handleLongHeaderPacket(p receivedPacket, hdr *wire.Header) bool /* was the packet successfully processed */ {
 	var wasQueued bool
 
@@ -1194,6 +1340,7 @@ func (s *connection) handleUnpackedLongHeaderPacket(
 	}
 
 	s.lastPacketReceivedTime = rcvTime
+	s.lastActivity.Store(rcvTime.UnixNano())
 	s.firstAckElicitingPacketAfterIdleSentTime = time.Time{}
 	s.keepAlivePingSent = false
 
@@ -1219,6 +1366,7 @@ func (s *connection) handleUnpackedShortHeaderPacket(
 	log func([]logging.Frame),
 ) error {
 	s.lastPacketReceivedTime = rcvTime
+	s.lastActivity.Store(rcvTime.UnixNano())
 	s.firstAckElicitingPacketAfterIdleSentTime = time.Time{}
 	s.keepAlivePingSent = false
 
@@ -1248,10 +1396,15 @@ func (s *connection) handleFrames(
 		if err != nil {
 			return false, err
 		}
+		raw := data[:l]
 		data = data[l:]
 		if frame == nil {
 			break
 		}
+		if s.tracer != nil && s.tracer.ReceivedRawFrame != nil {
+			// skip the PADDING preceding the frame
+			s.tracer.ReceivedRawFrame(encLevel, logutils.ConvertFrame(frame), bytes.TrimLeft(raw, "\x00"))
+		}
 		if ackhandler.IsFrameAckEliciting(frame) {
 			isAckEliciting = true
 		}
@@ -1313,6 +1466,7 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 	case *wire.MaxStreamsFrame:
 		s.handleMaxStreamsFrame(frame)
 	case *wire.DataBlockedFrame:
+		s.handleDataBlockedFrame(frame)
 	case *wire.StreamDataBlockedFrame:
 	case *wire.StreamsBlockedFrame:
 	case *wire.StopSendingFrame:
@@ -1321,8 +1475,7 @@ func (s *connection) handleFrame(f wire.Frame, encLevel protocol.EncryptionLevel
 	case *wire.PathChallengeFrame:
 		s.handlePathChallengeFrame(frame)
 	case *wire.PathResponseFrame:
-		// since we don't send PATH_CHALLENGEs, we don't expect PATH_RESPONSEs
-		err = errors.New("unexpected PATH_RESPONSE frame")
+		err = s.handlePathResponseFrame(frame)
 	case *wire.NewTokenFrame:
 		err = s.handleNewTokenFrame(frame)
 	case *wire.NewConnectionIDFrame:
@@ -1369,8 +1522,22 @@ func (s *connection) handleConnectionCloseFrame(frame *wire.ConnectionCloseFrame
 	})
 }
 
+func (s *connection) handleDataBlockedFrame(frame *wire.DataBlockedFrame) {
+	if s.logger.Debug() {
+		s.logger.Debugf("Peer blocked by connection-level flow control at %d. Flow control state: %s", frame.MaximumData, s.connFlowController.State())
+	}
+	// The peer is blocked on connection-level flow control.
+	// Check if we can already grant more flow control credit.
+	// If no window update is necessary yet, no MAX_DATA frame will be sent.
+	s.onHasConnectionWindowUpdate()
+}
+
 func (s *connection) handleCryptoFrame(frame *wire.CryptoFrame, encLevel protocol.EncryptionLevel) error {
-	if err := s.cryptoStreamManager.HandleCryptoFrame(frame, encLevel); err != nil {
+	processed, err := s.cryptoStreamManager.HandleCryptoFrame(frame, encLevel)
+	if processed {
+		s.lastHandshakeProgressTime = s.lastPacketReceivedTime
+	}
+	if err != nil {
 		return err
 	}
 	return s.handleHandshakeEvents()
@@ -1472,6 +1639,23 @@ func (s *connection) handlePathChallengeFrame(frame *wire.PathChallengeFrame) {
 	s.queueControlFrame(&wire.PathResponseFrame{Data: frame.Data})
 }
 
+func (s *connection) handlePathResponseFrame(frame *wire.PathResponseFrame) error {
+	if probe, ok := s.pathProbes[frame.Data]; ok {
+		// RFC 9000, section 8.2.3: A PATH_RESPONSE frame received on any network path validates the path
+		// on which the PATH_CHALLENGE was sent.
+		s.finishPathProbe(probe, true, nil)
+		return nil
+	}
+	if _, ok := s.finishedPathProbes[frame.Data]; ok {
+		// This is a duplicate response, or the response to a path probe that already timed out.
+		return nil
+	}
+	return &qerr.TransportError{
+		ErrorCode:    qerr.ProtocolViolation,
+		ErrorMessage: "received PATH_RESPONSE for an unknown PATH_CHALLENGE",
+	}
+}
+
 func (s *connection) handleNewTokenFrame(frame *wire.NewTokenFrame) error {
 	if s.perspective == protocol.PerspectiveServer {
 		return &qerr.TransportError{
@@ -1608,6 +1792,8 @@ func (s *connection) handleCloseError(closeErr *closeError) {
 	switch {
 	case errors.Is(e, qerr.ErrIdleTimeout),
 		errors.Is(e, qerr.ErrHandshakeTimeout),
+		errors.Is(e, qerr.ErrHandshakeNoProgress),
+		errors.Is(e, qerr.ErrMaxConnectionDuration),
 		errors.As(e, &statelessResetErr),
 		errors.As(e, &versionNegotiationErr),
 		errors.As(e, &recreateErr),
@@ -1629,6 +1815,11 @@ func (s *connection) handleCloseError(closeErr *closeError) {
 	if s.tracer != nil && s.tracer.ClosedConnection != nil && !errors.As(e, &recreateErr) {
 		s.tracer.ClosedConnection(e)
 	}
+	// Closing the connection using CloseWithError is not an error condition.
+	if s.config.OnConnectionError != nil && closeErr.err != nil && !errors.As(e, &recreateErr) &&
+		!(errors.As(e, &applicationErr) && !applicationErr.Remote) {
+		s.config.OnConnectionError(e)
+	}
 
 	// If this is a remote close we're done here
 	if closeErr.remote {
@@ -1840,7 +2031,7 @@ func (s *connection) sendPackets(now time.Time) error {
 			return err
 		}
 		ecn := s.sentPacketHandler.ECNMode(true)
-		s.logShortHeaderPacket(p.DestConnID, p.Ack, p.Frames, p.StreamFrames, p.PacketNumber, p.PacketNumberLen, p.KeyPhase, ecn, buf.Len(), false)
+		s.logShortHeaderPacket(p.DestConnID, p.Ack, p.Frames, p.StreamFrames, p.RawFrames, p.PacketNumber, p.PacketNumberLen, p.KeyPhase, ecn, buf.Len(), false)
 		s.registerPackedShortHeaderPacket(p, ecn, now)
 		s.sendQueue.Send(buf, 0, ecn)
 		// This is kind of a hack. We need to trigger sending again somehow.
@@ -1849,6 +2040,9 @@ func (s *connection) sendPackets(now time.Time) error {
 	}
 
 	if isBlocked, offset := s.connFlowController.IsNewlyBlocked(); isBlocked {
+		if s.logger.Debug() {
+			s.logger.Debugf("Blocked by connection-level flow control. Flow control state: %s", s.connFlowController.State())
+		}
 		s.framer.QueueControlFrame(&wire.DataBlockedFrame{MaximumData: offset})
 	}
 	s.windowUpdateQueue.QueueAll()
@@ -1918,6 +2112,7 @@ func (s *connection) sendPacketsWithGSO(now time.Time) error {
 	maxSize := s.mtuDiscoverer.CurrentSize()
 
 	ecn := s.sentPacketHandler.ECNMode(true)
+	var numPackets int // number of packets in the current batch
 	for {
 		var dontSendMore bool
 		size, err := s.appendOneShortHeaderPacket(buf, maxSize, ecn, now)
@@ -1930,6 +2125,8 @@ func (s *connection) sendPacketsWithGSO(now time.Time) error {
 				return nil
 			}
 			dontSendMore = true
+		} else {
+			numPackets++
 		}
 
 		if !dontSendMore {
@@ -1950,7 +2147,9 @@ func (s *connection) sendPacketsWithGSO(now time.Time) error {
 		// 2. The last packet appended was a full-size packet
 		// 3. The next packet will have the same ECN marking
 		// 4. We still have enough space for another full-size packet in the buffer
-		if !dontSendMore && size == maxSize && nextECN == ecn && buf.Len()+maxSize <= buf.Cap() {
+		// 5. The batch doesn't exceed the configured maximum number of packets
+		if !dontSendMore && size == maxSize && nextECN == ecn && buf.Len()+maxSize <= buf.Cap() &&
+			numPackets < s.config.MaxGSOBatchSize {
 			continue
 		}
 
@@ -1970,6 +2169,7 @@ func (s *connection) sendPacketsWithGSO(now time.Time) error {
 		}
 
 		buf = getLargePacketBuffer()
+		numPackets = 0
 	}
 }
 
@@ -2002,7 +2202,7 @@ func (s *connection) maybeSendAckOnlyPacket(now time.Time) error {
 		}
 		return err
 	}
-	s.logShortHeaderPacket(p.DestConnID, p.Ack, p.Frames, p.StreamFrames, p.PacketNumber, p.PacketNumberLen, p.KeyPhase, ecn, buf.Len(), false)
+	s.logShortHeaderPacket(p.DestConnID, p.Ack, p.Frames, p.StreamFrames, p.RawFrames, p.PacketNumber, p.PacketNumberLen, p.KeyPhase, ecn, buf.Len(), false)
 	s.registerPackedShortHeaderPacket(p, ecn, now)
 	s.sendQueue.Send(buf, 0, ecn)
 	return nil
@@ -2048,7 +2248,7 @@ func (s *connection) appendOneShortHeaderPacket(buf *packetBuffer, maxSize proto
 		return 0, err
 	}
 	size := buf.Len() - startLen
-	s.logShortHeaderPacket(p.DestConnID, p.Ack, p.Frames, p.StreamFrames, p.PacketNumber, p.PacketNumberLen, p.KeyPhase, ecn, size, false)
+	s.logShortHeaderPacket(p.DestConnID, p.Ack, p.Frames, p.StreamFrames, p.RawFrames, p.PacketNumber, p.PacketNumberLen, p.KeyPhase, ecn, size, false)
 	s.registerPackedShortHeaderPacket(p, ecn, now)
 	return size, nil
 }
@@ -2062,8 +2262,9 @@ func (s *connection) registerPackedShortHeaderPacket(p shortHeaderPacket, ecn pr
 	if p.Ack != nil {
 		largestAcked = p.Ack.LargestAcked()
 	}
-	s.sentPacketHandler.SentPacket(now, p.PacketNumber, largestAcked, p.StreamFrames, p.Frames, protocol.Encryption1RTT, ecn, p.Length, p.IsPathMTUProbePacket)
+	s.sentPacketHandler.SentPacket(now, p.PacketNumber, largestAcked, p.StreamFrames, p.Frames, protocol.Encryption1RTT, ecn, p.Length, p.IsPathMTUProbePacket, false)
 	s.connIDManager.SentPacket()
+	s.lastActivity.Store(now.UnixNano())
 }
 
 func (s *connection) sendPackedCoalescedPacket(packet *coalescedPacket, ecn protocol.ECN, now time.Time) error {
@@ -2076,7 +2277,7 @@ func (s *connection) sendPackedCoalescedPacket(packet *coalescedPacket, ecn prot
 		if p.ack != nil {
 			largestAcked = p.ack.LargestAcked()
 		}
-		s.sentPacketHandler.SentPacket(now, p.header.PacketNumber, largestAcked, p.streamFrames, p.frames, p.EncryptionLevel(), ecn, p.length, false)
+		s.sentPacketHandler.SentPacket(now, p.header.PacketNumber, largestAcked, p.streamFrames, p.frames, p.EncryptionLevel(), ecn, p.length, false, false)
 		if s.perspective == protocol.PerspectiveClient && p.EncryptionLevel() == protocol.EncryptionHandshake {
 			// On the client side, Initial keys are dropped as soon as the first Handshake packet is sent.
 			// See Section 4.9.1 of RFC 9001.
@@ -2093,9 +2294,10 @@ func (s *connection) sendPackedCoalescedPacket(packet *coalescedPacket, ecn prot
 		if p.Ack != nil {
 			largestAcked = p.Ack.LargestAcked()
 		}
-		s.sentPacketHandler.SentPacket(now, p.PacketNumber, largestAcked, p.StreamFrames, p.Frames, protocol.Encryption1RTT, ecn, p.Length, p.IsPathMTUProbePacket)
+		s.sentPacketHandler.SentPacket(now, p.PacketNumber, largestAcked, p.StreamFrames, p.Frames, protocol.Encryption1RTT, ecn, p.Length, p.IsPathMTUProbePacket, false)
 	}
 	s.connIDManager.SentPacket()
+	s.lastActivity.Store(now.UnixNano())
 	s.sendQueue.Send(packet.buffer, 0, ecn)
 	return nil
 }
@@ -2109,6 +2311,12 @@ func (s *connection) sendConnectionClose(e error) ([]byte, error) {
 		packet, err = s.packer.PackConnectionClose(transportErr, s.mtuDiscoverer.CurrentSize(), s.version)
 	} else if errors.As(e, &applicationErr) {
 		packet, err = s.packer.PackApplicationClose(applicationErr, s.mtuDiscoverer.CurrentSize(), s.version)
+	} else if errors.Is(e, qerr.ErrMaxConnectionDuration) {
+		// The application didn't do anything wrong, so there's no error code to send.
+		packet, err = s.packer.PackApplicationClose(&qerr.ApplicationError{
+			ErrorCode:    0,
+			ErrorMessage: e.Error(),
+		}, s.mtuDiscoverer.CurrentSize(), s.version)
 	} else {
 		packet, err = s.packer.PackConnectionClose(&qerr.TransportError{
 			ErrorCode:    qerr.InternalError,
@@ -2139,6 +2347,9 @@ func (s *connection) logLongHeaderPacket(p *longHeaderPacket, ecn protocol.ECN)
 	}
 
 	// tracing
+	if s.tracer != nil && s.tracer.SentRawFrame != nil {
+		s.traceSentRawFrames(p.EncryptionLevel(), p.ack, p.frames, p.streamFrames, p.rawFrames)
+	}
 	if s.tracer != nil && s.tracer.SentLongHeaderPacket != nil {
 		frames := make([]logging.Frame, 0, len(p.frames))
 		for _, f := range p.frames {
@@ -2160,6 +2371,7 @@ func (s *connection) logShortHeaderPacket(
 	ackFrame *wire.AckFrame,
 	frames []ackhandler.Frame,
 	streamFrames []ackhandler.StreamFrame,
+	rawFrames [][]byte,
 	pn protocol.PacketNumber,
 	pnLen protocol.PacketNumberLen,
 	kp protocol.KeyPhaseBit,
@@ -2185,6 +2397,9 @@ func (s *connection) logShortHeaderPacket(
 	}
 
 	// tracing
+	if s.tracer != nil && s.tracer.SentRawFrame != nil {
+		s.traceSentRawFrames(protocol.Encryption1RTT, ackFrame, frames, streamFrames, rawFrames)
+	}
 	if s.tracer != nil && s.tracer.SentShortHeaderPacket != nil {
 		fs := make([]logging.Frame, 0, len(frames)+len(streamFrames))
 		for _, f := range frames {
@@ -2212,6 +2427,33 @@ func (s *connection) logShortHeaderPacket(
 	}
 }
 
+// traceSentRawFrames traces the frames together with their serialized form, as recorded by the packet packer.
+// rawFrames contains the frames in the order they were packed: the ACK frame, the control frames, and then the STREAM frames.
+func (s *connection) traceSentRawFrames(encLevel protocol.EncryptionLevel, ack *wire.AckFrame, frames []ackhandler.Frame, streamFrames []ackhandler.StreamFrame, rawFrames [][]byte) {
+	numFrames := len(frames) + len(streamFrames)
+	if ack != nil {
+		numFrames++
+	}
+	if len(rawFrames) != numFrames {
+		s.logger.Errorf("connection BUG: recorded %d raw frames for %d frames, not tracing raw frames", len(rawFrames), numFrames)
+		return
+	}
+	if ack != nil {
+		// The serialized ACK frame might not contain all ACK ranges.
+		encodedAck := *ack
+		encodedAck.AckRanges = ack.EncodedAckRanges()
+		s.tracer.SentRawFrame(encLevel, logutils.ConvertFrame(&encodedAck), rawFrames[0])
+		rawFrames = rawFrames[1:]
+	}
+	for i, f := range frames {
+		s.tracer.SentRawFrame(encLevel, logutils.ConvertFrame(f.Frame), rawFrames[i])
+	}
+	rawFrames = rawFrames[len(frames):]
+	for i, f := range streamFrames {
+		s.tracer.SentRawFrame(encLevel, logutils.ConvertFrame(f.Frame), rawFrames[i])
+	}
+}
+
 func (s *connection) logCoalescedPacket(packet *coalescedPacket, ecn protocol.ECN) {
 	if s.logger.Debug() {
 		// There's a short period between dropping both Initial and Handshake keys and completion of the handshake,
@@ -2222,6 +2464,7 @@ func (s *connection) logCoalescedPacket(packet *coalescedPacket, ecn protocol.EC
 				packet.shortHdrPacket.Ack,
 				packet.shortHdrPacket.Frames,
 				packet.shortHdrPacket.StreamFrames,
+				packet.shortHdrPacket.RawFrames,
 				packet.shortHdrPacket.PacketNumber,
 				packet.shortHdrPacket.PacketNumberLen,
 				packet.shortHdrPacket.KeyPhase,
@@ -2241,7 +2484,7 @@ func (s *connection) logCoalescedPacket(packet *coalescedPacket, ecn protocol.EC
 		s.logLongHeaderPacket(p, ecn)
 	}
 	if p := packet.shortHdrPacket; p != nil {
-		s.logShortHeaderPacket(p.DestConnID, p.Ack, p.Frames, p.StreamFrames, p.PacketNumber, p.PacketNumberLen, p.KeyPhase, ecn, p.Length, true)
+		s.logShortHeaderPacket(p.DestConnID, p.Ack, p.Frames, p.StreamFrames, p.RawFrames, p.PacketNumber, p.PacketNumberLen, p.KeyPhase, ecn, p.Length, true)
 	}
 }
 
@@ -2340,6 +2583,11 @@ func (s *connection) onHasStreamData(id protocol.StreamID) {
 	s.scheduleSending()
 }
 
+func (s *connection) onHasStreamRetransmission(id protocol.StreamID) {
+	s.framer.AddStreamWithRetransmission(id)
+	s.scheduleSending()
+}
+
 func (s *connection) onStreamCompleted(id protocol.StreamID) {
 	if err := s.streamsMap.DeleteStream(id); err != nil {
 		s.closeLocal(err)
@@ -2369,6 +2617,183 @@ func (s *connection) ReceiveDatagram(ctx context.Context) ([]byte, error) {
 	return s.datagramQueue.Receive(ctx)
 }
 
+// A pathProbe is a PATH_CHALLENGE sent from a different local address, see ProbePath.
+type pathProbe struct {
+	conn rawConn
+	data [8]byte
+
+	// only accessed by the run loop
+	deadline     time.Time
+	hasConnID    bool
+	connIDSeqNum uint64 // the sequence number of the connection ID used for probing
+
+	// done is closed by the run loop when the probe finished, after setting validated and err
+	done      chan struct{}
+	validated bool
+	err       error
+}
+
+func (s *connection) ProbePath(ctx context.Context, localAddr net.Addr) (bool, error) {
+	if s.perspective == protocol.PerspectiveServer {
+		return false, errors.New("only clients can probe paths")
+	}
+	select {
+	case <-s.HandshakeComplete():
+	default:
+		return false, errors.New("can't probe a path before the handshake completed")
+	}
+	// RFC 9000, section 9: If the peer sent the disable_active_migration transport parameter,
+	// an endpoint also MUST NOT send packets (including probing packets) from a different local address.
+	if s.peerParams.DisableActiveMigration {
+		return false, errors.New("peer disabled active connection migration")
+	}
+	pc, err := net.ListenPacket(localAddr.Network(), localAddr.String())
+	if err != nil {
+		return false, err
+	}
+	conn := &basicConn{PacketConn: pc}
+	defer conn.Close()
+
+	probe := &pathProbe{conn: conn, done: make(chan struct{})}
+	if _, err := rand.Read(probe.data[:]); err != nil {
+		return false, err
+	}
+	// Packets received on the probed path are handled just like packets received on the active path.
+	go func() {
+		for {
+			p, err := conn.ReadPacket()
+			if err != nil {
+				return
+			}
+			s.handlePacket(p)
+		}
+	}()
+	s.pathProbeMutex.Lock()
+	s.queuedPathProbes = append(s.queuedPathProbes, probe)
+	s.pathProbeMutex.Unlock()
+	s.scheduleSending()
+
+	select {
+	case <-probe.done:
+		return probe.validated, probe.err
+	case <-ctx.Done():
+		s.dequeuePathProbe(probe)
+		return false, ctx.Err()
+	case <-s.ctx.Done():
+		return false, context.Cause(s.ctx)
+	}
+}
+
+// dequeuePathProbe removes a path probe that wasn't sent yet.
+// Path probes that were already sent are failed by the run loop once they time out.
+func (s *connection) dequeuePathProbe(probe *pathProbe) {
+	s.pathProbeMutex.Lock()
+	defer s.pathProbeMutex.Unlock()
+	for i, p := range s.queuedPathProbes {
+		if p == probe {
+			s.queuedPathProbes = append(s.queuedPathProbes[:i], s.queuedPathProbes[i+1:]...)
+			return
+		}
+	}
+}
+
+// handlePathProbes sends queued path probes, fails path probes that timed out,
+// and forgets about path probes that finished a while ago.
+func (s *connection) handlePathProbes(now time.Time) {
+	// RFC 9000, section 9: An endpoint MUST NOT initiate connection migration before the handshake is confirmed.
+	if s.handshakeConfirmed {
+		s.pathProbeMutex.Lock()
+		queue := s.queuedPathProbes
+		s.queuedPathProbes = nil
+		s.pathProbeMutex.Unlock()
+		for _, probe := range queue {
+			if err := s.sendPathProbe(probe, now); err != nil {
+				s.finishPathProbe(probe, false, err)
+			}
+		}
+	}
+	for _, probe := range s.pathProbes {
+		if !now.Before(probe.deadline) {
+			s.logger.Debugf("Path probe from %s timed out.", probe.conn.LocalAddr())
+			s.finishPathProbe(probe, false, nil)
+		}
+	}
+	for data, expiry := range s.finishedPathProbes {
+		if !now.Before(expiry) {
+			delete(s.finishedPathProbes, data)
+		}
+	}
+}
+
+func (s *connection) sendPathProbe(probe *pathProbe, now time.Time) error {
+	// RFC 9000, section 9.5: An endpoint MUST NOT reuse a connection ID when sending from more than one local address.
+	// This doesn't apply if the peer uses zero-length connection IDs.
+	var connID protocol.ConnectionID
+	if seq, c, ok := s.connIDManager.GetUnused(); ok {
+		connID = c
+		probe.hasConnID = true
+		probe.connIDSeqNum = seq
+	} else if s.handshakeDestConnID.Len() > 0 {
+		return errors.New("no unused connection ID available")
+	}
+	pathChallenge := ackhandler.Frame{Frame: &wire.PathChallengeFrame{Data: probe.data}}
+	p, buf, err := s.packer.PackPathProbePacket(connID, pathChallenge, s.version)
+	if err != nil {
+		return err
+	}
+	s.logShortHeaderPacket(p.DestConnID, p.Ack, p.Frames, p.StreamFrames, p.RawFrames, p.PacketNumber, p.PacketNumberLen, p.KeyPhase, protocol.ECNNon, buf.Len(), false)
+	// The probe packet is sent on a different path, and therefore not registered like packets sent on the current path:
+	// It is neither tracked for congestion control and loss detection,
+	// nor does it count towards the number of packets sent with the current connection ID.
+	s.sentPacketHandler.SentPacket(now, p.PacketNumber, protocol.InvalidPacketNumber, nil, p.Frames, protocol.Encryption1RTT, protocol.ECNNon, p.Length, false, true)
+	// The packet is sent without an ECN marking.
+	_, err = probe.conn.WritePacket(buf.Data, s.conn.RemoteAddr(), nil, 0, protocol.ECNUnsupported)
+	buf.Release()
+	if err != nil {
+		return err
+	}
+	// RFC 9000, section 8.2.4: Use three times the larger of the current PTO and the PTO for the new path
+	// (using the initial RTT).
+	var newPathRTTStats utils.RTTStats
+	probe.deadline = now.Add(3 * max(s.rttStats.PTO(true), newPathRTTStats.PTO(true)))
+	if s.pathProbes == nil {
+		s.pathProbes = make(map[[8]byte]*pathProbe)
+	}
+	s.pathProbes[probe.data] = probe
+	return nil
+}
+
+func (s *connection) finishPathProbe(probe *pathProbe, validated bool, err error) {
+	delete(s.pathProbes, probe.data)
+	// Probes that failed to be sent have no deadline. The peer can't respond to those.
+	if !probe.deadline.IsZero() {
+		// The peer's PATH_RESPONSE might be delayed, or it might have responded multiple times.
+		// Accept responses for another three PTOs after the probe would have timed out.
+		if s.finishedPathProbes == nil {
+			s.finishedPathProbes = make(map[[8]byte]time.Time)
+		}
+		s.finishedPathProbes[probe.data] = probe.deadline.Add(3 * s.rttStats.PTO(true))
+	}
+	if probe.hasConnID {
+		s.connIDManager.Retire(probe.connIDSeqNum)
+	}
+	probe.validated = validated
+	probe.err = err
+	close(probe.done)
+}
+
+// pathProbeDeadline returns the time when the next outstanding path probe times out.
+// It returns the zero value if there are no outstanding path probes.
+func (s *connection) pathProbeDeadline() time.Time {
+	var deadline time.Time
+	for _, probe := range s.pathProbes {
+		if deadline.IsZero() || probe.deadline.Before(deadline) {
+			deadline = probe.deadline
+		}
+	}
+	return deadline
+}
+
 func (s *connection) LocalAddr() net.Addr {
 	return s.conn.LocalAddr()
 }
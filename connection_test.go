@@ -8,13 +8,16 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net"
 	"runtime/pprof"
 	"strings"
 	"time"
 
 	"github.com/quic-go/quic-go/internal/ackhandler"
+	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/handshake"
+	"github.com/quic-go/quic-go/internal/logutils"
 	"github.com/quic-go/quic-go/internal/mocks"
 	mockackhandler "github.com/quic-go/quic-go/internal/mocks/ackhandler"
 	mocklogging "github.com/quic-go/quic-go/internal/mocks/logging"
@@ -36,6 +39,73 @@ func areConnsRunning() bool {
 	return strings.Contains(b.String(), "quic-go.(*connection).run")
 }
 
+type timerType uint8
+
+const (
+	timerTypeAck           timerType = iota
+	timerTypeLossDetection           // time threshold loss detection and the PTO share a single timer
+	timerTypeIdle
+)
+
+// expiredAckAlarm is an ACK frame source that treats the ACK alarm as expired.
+// An ACK is pending (i.e. hasNewAck is set) if and only if an ACK is queued or the ACK alarm is set,
+// so requesting the ACK frame unconditionally is equivalent to requesting it after the alarm fired.
+type expiredAckAlarm struct {
+	ackhandler.ReceivedPacketHandler
+}
+
+func (h *expiredAckAlarm) GetAckFrame(encLevel protocol.EncryptionLevel, _ bool) *wire.AckFrame {
+	return h.ReceivedPacketHandler.GetAckFrame(encLevel, false)
+}
+
+// triggerTimerForTest fires a timer immediately, without waiting for its deadline.
+// It performs the same actions as the run loop does when the respective timer expires.
+// It returns an error if the timer is not set.
+// It must not be called while the run loop is running.
+func (s *connection) triggerTimerForTest(t timerType) error {
+	switch t {
+	case timerTypeAck:
+		if s.receivedPacketHandler.GetAlarmTimeout().IsZero() {
+			return errors.New("ACK timer not set")
+		}
+		rph := s.receivedPacketHandler
+		s.receivedPacketHandler = &expiredAckAlarm{ReceivedPacketHandler: rph}
+		defer func() { s.receivedPacketHandler = rph }()
+		if p, ok := s.packer.(*packetPacker); ok {
+			p.acks = s.receivedPacketHandler
+			defer func() { p.acks = rph }()
+		}
+	case timerTypeLossDetection:
+		// Whether the timer fires for time threshold loss detection or for the PTO is reported via the
+		// tracer's LossTimerExpired callback.
+		deadline := s.sentPacketHandler.GetLossDetectionTimeout()
+		if deadline.IsZero() {
+			return errors.New("loss detection timer not set")
+		}
+		// Fire the timer just after its deadline, as the run loop would.
+		if err := s.sentPacketHandler.OnLossDetectionTimeout(deadline.Add(time.Nanosecond)); err != nil {
+			return err
+		}
+	case timerTypeIdle:
+		// Before the handshake completes, the handshake idle timeout and the handshake timeout apply.
+		var deadline time.Time
+		if s.handshakeComplete {
+			deadline = s.nextIdleTimeoutTime()
+		} else {
+			deadline = utils.MinTime(
+				s.creationTime.Add(s.config.handshakeTimeout()),
+				s.idleTimeoutStartTime().Add(s.config.HandshakeIdleTimeout),
+			)
+		}
+		if destroyed := s.handleTimeouts(deadline.Add(time.Nanosecond)); destroyed {
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown timer type: %d", t)
+	}
+	return s.triggerSending(time.Now())
+}
+
 var _ = Describe("Connection", func() {
 	var (
 		conn          *connection
@@ -129,6 +199,7 @@ var _ = Describe("Connection", func() {
 		streamManager = NewMockStreamManager(mockCtrl)
 		conn.streamsMap = streamManager
 		packer = NewMockPacker(mockCtrl)
+		packer.EXPECT().SetSpinBit(gomock.Any()).AnyTimes()
 		conn.packer = packer
 		cryptoSetup = mocks.NewMockCryptoSetup(mockCtrl)
 		conn.cryptoStreamHandler = cryptoSetup
@@ -175,6 +246,23 @@ var _ = Describe("Connection", func() {
 			})
 		})
 
+		Context("handling CRYPTO frames", func() {
+			It("records handshake progress when a new handshake message is processed", func() {
+				conn.handshakeComplete = false
+				conn.cryptoStreamManager = newCryptoStreamManager(cryptoSetup, newCryptoStream(), newCryptoStream(), newCryptoStream())
+				rcvTime := time.Now().Add(time.Second)
+				conn.lastPacketReceivedTime = rcvTime
+				cryptoSetup.EXPECT().HandleMessage([]byte("foobar"), protocol.EncryptionHandshake)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent}).Times(2)
+				Expect(conn.handleFrame(&wire.CryptoFrame{Data: []byte("foobar")}, protocol.EncryptionHandshake, protocol.ConnectionID{})).To(Succeed())
+				Expect(conn.lastHandshakeProgressTime).To(Equal(rcvTime))
+				// a retransmission doesn't advance the handshake
+				conn.lastPacketReceivedTime = rcvTime.Add(time.Second)
+				Expect(conn.handleFrame(&wire.CryptoFrame{Data: []byte("foobar")}, protocol.EncryptionHandshake, protocol.ConnectionID{})).To(Succeed())
+				Expect(conn.lastHandshakeProgressTime).To(Equal(rcvTime))
+			})
+		})
+
 		Context("handling ACK frames", func() {
 			It("informs the SentPacketHandler about ACKs", func() {
 				f := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 3}}}
@@ -305,7 +393,56 @@ var _ = Describe("Connection", func() {
 
 		It("rejects PATH_RESPONSE frames", func() {
 			err := conn.handleFrame(&wire.PathResponseFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}, protocol.Encryption1RTT, protocol.ConnectionID{})
-			Expect(err).To(MatchError("unexpected PATH_RESPONSE frame"))
+			Expect(err).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.ProtocolViolation,
+				ErrorMessage: "received PATH_RESPONSE for an unknown PATH_CHALLENGE",
+			}))
+		})
+
+		Context("PATH_RESPONSE frames for path probes", func() {
+			newProbe := func(data [8]byte, deadline time.Time) *pathProbe {
+				rawConn := NewMockRawConn(mockCtrl)
+				rawConn.EXPECT().LocalAddr().Return(&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1337}).AnyTimes()
+				probe := &pathProbe{conn: rawConn, data: data, deadline: deadline, done: make(chan struct{})}
+				conn.pathProbes = map[[8]byte]*pathProbe{data: probe}
+				return probe
+			}
+
+			It("validates the path, and ignores duplicate PATH_RESPONSE frames", func() {
+				data := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+				probe := newProbe(data, time.Now().Add(time.Hour))
+				Expect(conn.handleFrame(&wire.PathResponseFrame{Data: data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(probe.done).To(BeClosed())
+				Expect(probe.validated).To(BeTrue())
+				Expect(conn.handleFrame(&wire.PathResponseFrame{Data: data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			})
+
+			It("ignores PATH_RESPONSE frames for path probes that already timed out", func() {
+				data := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+				now := time.Now()
+				probe := newProbe(data, now)
+				conn.handlePathProbes(now)
+				Expect(probe.done).To(BeClosed())
+				Expect(probe.validated).To(BeFalse())
+				Expect(conn.handleFrame(&wire.PathResponseFrame{Data: data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				// the probe is forgotten after another three PTOs
+				conn.handlePathProbes(now.Add(3 * conn.rttStats.PTO(true)))
+				err := conn.handleFrame(&wire.PathResponseFrame{Data: data}, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
+			})
+
+			It("rejects unsolicited PATH_RESPONSE frames after a path probe completed", func() {
+				data := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+				newProbe(data, time.Now().Add(time.Hour))
+				Expect(conn.handleFrame(&wire.PathResponseFrame{Data: data}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+				Expect(conn.pathProbes).ToNot(BeNil())
+				err := conn.handleFrame(&wire.PathResponseFrame{Data: [8]byte{8, 7, 6, 5, 4, 3, 2, 1}}, protocol.Encryption1RTT, protocol.ConnectionID{})
+				Expect(err).To(MatchError(&qerr.TransportError{
+					ErrorCode:    qerr.ProtocolViolation,
+					ErrorMessage: "received PATH_RESPONSE for an unknown PATH_CHALLENGE",
+				}))
+			})
 		})
 
 		It("handles PATH_CHALLENGE frames", func() {
@@ -328,6 +465,33 @@ var _ = Describe("Connection", func() {
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("considers sending a MAX_DATA frame when receiving a DATA_BLOCKED frame", func() {
+			connFC := mocks.NewMockConnectionFlowController(mockCtrl)
+			conn.windowUpdateQueue = newWindowUpdateQueue(streamManager, connFC, conn.framer.QueueControlFrame)
+			err := conn.handleFrame(&wire.DataBlockedFrame{MaximumData: 1000}, protocol.Encryption1RTT, protocol.ConnectionID{})
+			Expect(err).NotTo(HaveOccurred())
+			connFC.EXPECT().GetWindowUpdate().Return(protocol.ByteCount(2000))
+			conn.windowUpdateQueue.QueueAll()
+			frames, _ := conn.framer.AppendControlFrames(nil, 1000, protocol.Version1)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(&wire.MaxDataFrame{MaximumData: 2000}))
+		})
+
+		It("logs the flow control state when receiving a DATA_BLOCKED frame", func() {
+			buf := &bytes.Buffer{}
+			log.SetOutput(buf)
+			defer log.SetOutput(io.Discard)
+			logger := utils.DefaultLogger.WithPrefix("test")
+			logger.SetLogLevel(utils.LogLevelDebug)
+			conn.logger = logger
+			connFC := mocks.NewMockConnectionFlowController(mockCtrl)
+			connFC.EXPECT().State().Return(flowcontrol.State{BytesRead: 100, HighestReceived: 200, ReceiveWindow: 1000})
+			conn.connFlowController = connFC
+			conn.handleDataBlockedFrame(&wire.DataBlockedFrame{MaximumData: 1000})
+			Expect(buf.String()).To(ContainSubstring("Peer blocked by connection-level flow control at 1000"))
+			Expect(buf.String()).To(ContainSubstring("received: 200 (read: 100, window: 1000)"))
+		})
+
 		It("handles STREAM_BLOCKED frames", func() {
 			err := conn.handleFrame(&wire.StreamDataBlockedFrame{}, protocol.Encryption1RTT, protocol.ConnectionID{})
 			Expect(err).NotTo(HaveOccurred())
@@ -373,6 +537,8 @@ var _ = Describe("Connection", func() {
 				ErrorCode:    0x1337,
 				ErrorMessage: "foobar",
 			}
+			connErrChan := make(chan error, 1)
+			conn.config.OnConnectionError = func(e error) { connErrChan <- e }
 			streamManager.EXPECT().CloseWithError(testErr)
 			connRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(connIDs []protocol.ConnectionID, _ protocol.Perspective, _ []byte) {
 				Expect(connIDs).To(ConsistOf(clientDestConnID, srcConnID))
@@ -397,6 +563,8 @@ var _ = Describe("Connection", func() {
 			Expect(conn.handleFrame(ccf, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
 			Eventually(conn.Context().Done()).Should(BeClosed())
 			Expect(context.Cause(conn.Context())).To(MatchError(testErr))
+			Eventually(connErrChan).Should(Receive(MatchError(testErr)))
+			Consistently(connErrChan).ShouldNot(Receive())
 		})
 
 		It("errors on HANDSHAKE_DONE frames", func() {
@@ -405,6 +573,156 @@ var _ = Describe("Connection", func() {
 				ErrorMessage: "received a HANDSHAKE_DONE frame",
 			}))
 		})
+
+		It("traces the raw bytes of sent frames", func() {
+			type rawFrame struct {
+				frame logging.Frame
+				raw   []byte
+			}
+			var traced []rawFrame
+			conn.tracer.SentRawFrame = func(encLevel logging.EncryptionLevel, f logging.Frame, b []byte) {
+				defer GinkgoRecover()
+				Expect(encLevel).To(Equal(protocol.Encryption1RTT))
+				traced = append(traced, rawFrame{frame: f, raw: append([]byte{}, b...)})
+			}
+			tracer.EXPECT().SentShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}}
+			frames := []ackhandler.Frame{{Frame: &wire.MaxDataFrame{MaximumData: 1337}}, {Frame: &wire.PingFrame{}}}
+			streamFrames := []ackhandler.StreamFrame{{Frame: &wire.StreamFrame{StreamID: 4, Offset: 100, Data: []byte("foobar")}}}
+			// the raw frames are recorded by the packet packer
+			var rawFrames [][]byte
+			for _, f := range []wire.Frame{ack, frames[0].Frame, frames[1].Frame, streamFrames[0].Frame} {
+				b, err := f.Append(nil, conn.version)
+				Expect(err).ToNot(HaveOccurred())
+				rawFrames = append(rawFrames, b)
+			}
+			conn.logShortHeaderPacket(
+				protocol.ParseConnectionID([]byte{1, 2, 3}),
+				ack,
+				frames,
+				streamFrames,
+				rawFrames,
+				1337,
+				protocol.PacketNumberLen2,
+				protocol.KeyPhaseOne,
+				protocol.ECNNon,
+				100,
+				false,
+			)
+			Expect(traced).To(HaveLen(4))
+			parser := wire.NewFrameParser(false)
+			for i, f := range traced {
+				Expect(f.raw).To(Equal(rawFrames[i]))
+				l, frame, err := parser.ParseNext(f.raw, protocol.Encryption1RTT, conn.version)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(l).To(Equal(len(f.raw)))
+				Expect(logutils.ConvertFrame(frame)).To(Equal(f.frame))
+			}
+		})
+
+		It("only traces the ACK ranges that were serialized", func() {
+			var traced []logging.Frame
+			var tracedRaw [][]byte
+			conn.tracer.SentRawFrame = func(_ logging.EncryptionLevel, f logging.Frame, b []byte) {
+				traced = append(traced, f)
+				tracedRaw = append(tracedRaw, append([]byte{}, b...))
+			}
+			tracer.EXPECT().SentShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			ack := &wire.AckFrame{
+				AckRanges: []wire.AckRange{
+					{Smallest: 20, Largest: 25},
+					{Smallest: 15, Largest: 18},
+					{Smallest: 10, Largest: 12},
+					{Smallest: 5, Largest: 7},
+				},
+				MaxNumRanges: 2,
+			}
+			b, err := ack.Append(nil, conn.version)
+			Expect(err).ToNot(HaveOccurred())
+			conn.logShortHeaderPacket(
+				protocol.ParseConnectionID([]byte{1, 2, 3}),
+				ack,
+				nil,
+				nil,
+				[][]byte{b},
+				1337,
+				protocol.PacketNumberLen2,
+				protocol.KeyPhaseOne,
+				protocol.ECNNon,
+				100,
+				false,
+			)
+			Expect(traced).To(HaveLen(1))
+			Expect(tracedRaw[0]).To(Equal(b))
+			Expect(traced[0]).To(BeAssignableToTypeOf(&logging.AckFrame{}))
+			Expect(traced[0].(*logging.AckFrame).AckRanges).To(Equal(ack.AckRanges[:2]))
+			l, frame, err := wire.NewFrameParser(false).ParseNext(tracedRaw[0], protocol.Encryption1RTT, conn.version)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(l).To(Equal(len(b)))
+			Expect(logutils.ConvertFrame(frame)).To(Equal(traced[0]))
+		})
+
+		It("traces the raw bytes of received frames", func() {
+			type rawFrame struct {
+				frame logging.Frame
+				raw   []byte
+			}
+			var traced []rawFrame
+			conn.tracer.ReceivedRawFrame = func(encLevel logging.EncryptionLevel, f logging.Frame, b []byte) {
+				defer GinkgoRecover()
+				Expect(encLevel).To(Equal(protocol.Encryption1RTT))
+				traced = append(traced, rawFrame{frame: f, raw: append([]byte{}, b...)})
+			}
+			data := []byte{0, 0, 0} // PADDING
+			data, _ = (&wire.PingFrame{}).Append(data, conn.version)
+			data, _ = (&wire.StreamsBlockedFrame{Type: protocol.StreamTypeBidi, StreamLimit: 42}).Append(data, conn.version)
+			data = append(data, 0, 0)
+			data, _ = (&wire.StreamDataBlockedFrame{StreamID: 4, MaximumStreamData: 1337}).Append(data, conn.version)
+			data = append(data, 0, 0) // trailing PADDING
+			_, err := conn.handleFrames(data, protocol.ParseConnectionID([]byte{1, 2, 3}), protocol.Encryption1RTT, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(traced).To(HaveLen(3))
+			parser := wire.NewFrameParser(false)
+			for _, f := range traced {
+				Expect(f.raw[0]).ToNot(BeZero())
+				l, frame, err := parser.ParseNext(f.raw, protocol.Encryption1RTT, conn.version)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(l).To(Equal(len(f.raw)))
+				Expect(logutils.ConvertFrame(frame)).To(Equal(f.frame))
+			}
+		})
+
+		It("doesn't exceed the amplification limit when flooded with PATH_CHALLENGE frames", func() {
+			conn.handshakeComplete = false
+			sender := NewMockSender(mockCtrl)
+			sender.EXPECT().WouldBlock().AnyTimes()
+			conn.sendQueue = sender
+			// receive a single 1000 byte datagram, stuffed with PATH_CHALLENGE frames
+			conn.sentPacketHandler.ReceivedBytes(1000)
+			for i := 0; i < 100; i++ {
+				Expect(conn.handleFrame(&wire.PathChallengeFrame{Data: [8]byte{byte(i)}}, protocol.Encryption1RTT, protocol.ConnectionID{})).To(Succeed())
+			}
+			var pn protocol.PacketNumber
+			packer.EXPECT().PackCoalescedPacket(false, gomock.Any(), conn.version).DoAndReturn(func(bool, protocol.ByteCount, protocol.VersionNumber) (*coalescedPacket, error) {
+				frames, _ := conn.framer.AppendControlFrames(nil, protocol.MaxByteCount, conn.version)
+				p := getCoalescedPacket(pn, false)
+				p.shortHdrPacket.Frames = frames
+				p.shortHdrPacket.Length = 1000
+				pn++
+				return p, nil
+			}).AnyTimes()
+			tracer.EXPECT().SentShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SetLossTimer(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().LossTimerCanceled().AnyTimes()
+			var numSent int
+			sender.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(*packetBuffer, uint16, protocol.ECN) { numSent++ }).AnyTimes()
+			for i := 0; i < 10; i++ {
+				Expect(conn.triggerSending(time.Now())).To(Succeed())
+			}
+			// we're allowed to send 3x the number of bytes received
+			Expect(numSent).To(Equal(3))
+		})
 	})
 
 	It("tells its versions", func() {
@@ -485,6 +803,8 @@ var _ = Describe("Connection", func() {
 		})
 
 		It("closes with an error", func() {
+			var calledOnConnectionError bool
+			conn.config.OnConnectionError = func(error) { calledOnConnectionError = true }
 			runConn()
 			expectedErr := &qerr.ApplicationError{
 				ErrorCode:    0x1337,
@@ -503,6 +823,57 @@ var _ = Describe("Connection", func() {
 			Eventually(areConnsRunning).Should(BeFalse())
 			Expect(conn.Context().Done()).To(BeClosed())
 			Expect(context.Cause(conn.Context())).To(MatchError(expectedErr))
+			// closing the connection using CloseWithError is not an error condition
+			Expect(calledOnConnectionError).To(BeFalse())
+		})
+
+		It("closes without sending a CONNECTION_CLOSE when the packet number space is exhausted", func() {
+			sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
+			conn.sentPacketHandler = sph
+			packer.EXPECT().PackCoalescedPacket(false, gomock.Any(), conn.version).Return(nil, errPacketNumberSpaceExhausted)
+			streamManager.EXPECT().CloseWithError(errPacketNumberSpaceExhausted)
+			connRunner.EXPECT().Remove(gomock.Any()).AnyTimes()
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(errPacketNumberSpaceExhausted),
+				tracer.EXPECT().Close(),
+			)
+			// don't EXPECT any calls to PackConnectionClose or PackApplicationClose
+			expectedRunErr = errPacketNumberSpaceExhausted
+			runConn()
+			conn.scheduleSending()
+			Eventually(areConnsRunning).Should(BeFalse())
+			Expect(conn.Context().Done()).To(BeClosed())
+		})
+
+		It("handles the peer's CONNECTION_CLOSE when closing at the same time", func() {
+			runConn()
+			expectedErr := &qerr.ApplicationError{
+				ErrorCode:    0x1337,
+				ErrorMessage: "test error",
+			}
+			streamManager.EXPECT().CloseWithError(expectedErr)
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackApplicationClose(expectedErr, gomock.Any(), conn.version).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(expectedErr),
+				tracer.EXPECT().Close(),
+			)
+			expectedRunErr = expectedErr
+			conn.closeLocal(expectedErr)
+			// the peer's CONNECTION_CLOSE arrives while we're closing
+			conn.handleConnectionCloseFrame(&wire.ConnectionCloseFrame{
+				IsApplicationError: true,
+				ErrorCode:          0x42,
+				ReasonPhrase:       "peer closing",
+			})
+			Eventually(areConnsRunning).Should(BeFalse())
+			Expect(conn.Context().Done()).To(BeClosed())
+			Expect(context.Cause(conn.Context())).To(MatchError(expectedErr))
 		})
 
 		It("includes the frame type in transport-level close frames", func() {
@@ -605,6 +976,41 @@ var _ = Describe("Connection", func() {
 			Eventually(conn.Context().Done()).Should(BeClosed())
 		})
 
+		It("enters the draining state without acknowledging the packet containing the CONNECTION_CLOSE", func() {
+			unpacker := NewMockUnpacker(mockCtrl)
+			conn.handshakeConfirmed = true
+			conn.unpacker = unpacker
+			runConn()
+			cryptoSetup.EXPECT().Close()
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			// no CONNECTION_CLOSE packet is passed, so packets arriving in the draining state won't be responded to
+			connRunner.EXPECT().ReplaceWithClosed(gomock.Any(), gomock.Any(), []byte(nil))
+			b, err := wire.AppendShortHeader(nil, srcConnID, 42, protocol.PacketNumberLen2, protocol.KeyPhaseOne)
+			Expect(err).ToNot(HaveOccurred())
+
+			unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).DoAndReturn(func(time.Time, []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
+				// the PING frame makes this packet ack-eliciting
+				b, err := (&wire.PingFrame{}).Append(nil, conn.version)
+				Expect(err).ToNot(HaveOccurred())
+				b, err = (&wire.ConnectionCloseFrame{ErrorCode: uint64(qerr.NoError)}).Append(b, conn.version)
+				Expect(err).ToNot(HaveOccurred())
+				return 3, protocol.PacketNumberLen2, protocol.KeyPhaseOne, b, nil
+			})
+			gomock.InOrder(
+				tracer.EXPECT().ReceivedShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()),
+				tracer.EXPECT().ClosedConnection(gomock.Any()),
+				tracer.EXPECT().Close(),
+			)
+			// don't EXPECT any calls to the packer, nor to mconn.Write
+			conn.handlePacket(receivedPacket{
+				rcvTime:    time.Now(),
+				remoteAddr: &net.UDPAddr{},
+				buffer:     getPacketBuffer(),
+				data:       b,
+			})
+			Eventually(conn.Context().Done()).Should(BeClosed())
+		})
+
 		It("closes when the sendQueue encounters an error", func() {
 			conn.handshakeConfirmed = true
 			sconn := NewMockSendConn(mockCtrl)
@@ -616,7 +1022,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().ECNMode(true).Return(protocol.ECT1).AnyTimes()
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 			// only expect a single SentPacket() call
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			tracer.EXPECT().SentShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			tracer.EXPECT().ClosedConnection(gomock.Any())
 			tracer.EXPECT().Close()
@@ -805,6 +1211,77 @@ var _ = Describe("Connection", func() {
 			Expect(conn.handlePacketImpl(packet)).To(BeTrue())
 		})
 
+		Context("spin bit", func() {
+			var spinPacker *MockPacker
+
+			BeforeEach(func() {
+				spinPacker = NewMockPacker(mockCtrl)
+				conn.packer = spinPacker
+				conn.disableSpinBit = false
+				tracer.EXPECT().ReceivedShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			})
+
+			receivePacket := func(pn protocol.PacketNumber, spin bool) {
+				b, err := (&wire.PingFrame{}).Append(nil, conn.version)
+				Expect(err).ToNot(HaveOccurred())
+				packet := getShortHeaderPacket(srcConnID, pn, nil)
+				if spin {
+					packet.data[0] |= 0x20
+				}
+				unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(pn, protocol.PacketNumberLen2, protocol.KeyPhaseZero, b, nil)
+				Expect(conn.handlePacketImpl(packet)).To(BeTrue())
+			}
+
+			It("reflects the spin bit, for the server", func() {
+				spinPacker.EXPECT().SetSpinBit(true)
+				receivePacket(10, true)
+				// reordered packets don't change the spin bit
+				receivePacket(9, false)
+				spinPacker.EXPECT().SetSpinBit(false)
+				receivePacket(11, false)
+			})
+
+			It("inverts the spin bit, for the client", func() {
+				conn.perspective = protocol.PerspectiveClient
+				spinPacker.EXPECT().SetSpinBit(false)
+				receivePacket(10, true)
+				// reordered packets don't change the spin bit
+				receivePacket(9, false)
+				spinPacker.EXPECT().SetSpinBit(true)
+				receivePacket(11, false)
+			})
+
+			It("ignores the spin bit, if the spin bit is disabled", func() {
+				conn.disableSpinBit = true
+				receivePacket(10, true)
+				receivePacket(11, false)
+			})
+
+			It("disables the spin bit on a random selection of connections", func() {
+				const num = 16 * 1000
+				var disabled int
+				for i := 0; i < num; i++ {
+					if randomlyDisableSpinBit() {
+						disabled++
+					}
+				}
+				Expect(disabled).To(BeNumerically("~", num/16, num/16/4))
+			})
+		})
+
+		It("updates the time of the last activity when receiving a packet", func() {
+			Expect(conn.LastActivity()).To(BeZero())
+			rcvTime := time.Now().Add(-10 * time.Second)
+			b, err := (&wire.PingFrame{}).Append(nil, conn.version)
+			Expect(err).ToNot(HaveOccurred())
+			packet := getShortHeaderPacket(srcConnID, 0x37, nil)
+			unpacker.EXPECT().UnpackShortHeader(rcvTime, gomock.Any()).Return(protocol.PacketNumber(0x1337), protocol.PacketNumberLen2, protocol.KeyPhaseZero, b, nil)
+			packet.rcvTime = rcvTime
+			tracer.EXPECT().ReceivedShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			Expect(conn.handlePacketImpl(packet)).To(BeTrue())
+			Expect(conn.LastActivity()).To(BeTemporally("==", rcvTime))
+		})
+
 		It("drops duplicate packets", func() {
 			packet := getShortHeaderPacket(srcConnID, 0x37, nil)
 			unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(protocol.PacketNumber(0x1337), protocol.PacketNumberLen2, protocol.KeyPhaseOne, []byte("foobar"), nil)
@@ -942,6 +1419,43 @@ var _ = Describe("Connection", func() {
 			Eventually(conn.Context().Done()).Should(BeClosed())
 		})
 
+		It("closes the connection with the frame type of a malformed STREAM frame", func() {
+			b, err := (&wire.StreamFrame{
+				StreamID:       4,
+				Offset:         0x100,
+				Data:           []byte("foobar"),
+				DataLenPresent: true,
+			}).Append(nil, conn.version)
+			Expect(err).ToNot(HaveOccurred())
+			frameType := uint64(b[0])
+			unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2, protocol.KeyPhaseZero, b[:len(b)-2], nil)
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any(), gomock.Any(), conn.version).DoAndReturn(func(e *qerr.TransportError, _ protocol.ByteCount, _ protocol.VersionNumber) (*coalescedPacket, error) {
+				Expect(e.ErrorCode).To(Equal(qerr.FrameEncodingError))
+				Expect(e.FrameType).To(Equal(frameType))
+				return &coalescedPacket{buffer: getPacketBuffer()}, nil
+			})
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				err := conn.run()
+				Expect(err).To(HaveOccurred())
+				var transportErr *qerr.TransportError
+				Expect(errors.As(err, &transportErr)).To(BeTrue())
+				Expect(transportErr.FrameType).To(Equal(frameType))
+				close(done)
+			}()
+			expectReplaceWithClosed()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			conn.handlePacket(getShortHeaderPacket(srcConnID, 0x42, nil))
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("ignores packets when unpacking the header fails", func() {
 			testErr := &headerParseError{errors.New("test error")}
 			unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).Return(protocol.PacketNumber(0), protocol.PacketNumberLen(0), protocol.KeyPhaseBit(0), nil, testErr)
@@ -991,6 +1505,76 @@ var _ = Describe("Connection", func() {
 			Eventually(conn.Context().Done()).Should(BeClosed())
 		})
 
+		It("closes the connection with a PROTOCOL_VIOLATION when the server receives a HANDSHAKE_DONE frame", func() {
+			Expect(conn.perspective).To(Equal(protocol.PerspectiveServer))
+			unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).DoAndReturn(func(time.Time, []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
+				b, err := (&wire.HandshakeDoneFrame{}).Append(nil, conn.version)
+				Expect(err).ToNot(HaveOccurred())
+				return 10, protocol.PacketNumberLen2, protocol.KeyPhaseZero, b, nil
+			})
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any(), gomock.Any(), conn.version).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				err := conn.run()
+				Expect(err).To(MatchError(&qerr.TransportError{
+					ErrorCode:    qerr.ProtocolViolation,
+					ErrorMessage: "received a HANDSHAKE_DONE frame",
+				}))
+				close(done)
+			}()
+			expectReplaceWithClosed()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ReceivedShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			conn.handlePacket(getShortHeaderPacket(srcConnID, 0x42, nil))
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes the connection with a PROTOCOL_VIOLATION when receiving a frame that's not allowed in 0-RTT packets", func() {
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					Type:             protocol.PacketType0RTT,
+					DestConnectionID: srcConnID,
+					Length:           1,
+					Version:          conn.version,
+				},
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any(), gomock.Any(), conn.version).DoAndReturn(func(*wire.Header, time.Time, []byte, protocol.VersionNumber) (*unpackedPacket, error) {
+				b, err := (&wire.NewTokenFrame{Token: []byte("foobar")}).Append(nil, conn.version)
+				Expect(err).ToNot(HaveOccurred())
+				return &unpackedPacket{encryptionLevel: protocol.Encryption0RTT, hdr: hdr, data: b}, nil
+			})
+			streamManager.EXPECT().CloseWithError(gomock.Any())
+			cryptoSetup.EXPECT().Close()
+			packer.EXPECT().PackConnectionClose(gomock.Any(), gomock.Any(), conn.version).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				err := conn.run()
+				var transportErr *qerr.TransportError
+				Expect(errors.As(err, &transportErr)).To(BeTrue())
+				Expect(transportErr.ErrorCode).To(Equal(qerr.ProtocolViolation))
+				Expect(transportErr.ErrorMessage).To(ContainSubstring("not allowed at encryption level 0-RTT"))
+				close(done)
+			}()
+			expectReplaceWithClosed()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			conn.handlePacket(getLongHeaderPacket(hdr, nil))
+			Eventually(done).Should(BeClosed())
+		})
+
 		It("ignores packets with a different source connection ID", func() {
 			hdr1 := &wire.ExtendedHeader{
 				Header: wire.Header{
@@ -1239,7 +1823,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 			sph.EXPECT().ECNMode(true).Return(protocol.ECNNon).AnyTimes()
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			runConn()
 			p := shortHeaderPacket{
 				DestConnID:      protocol.ParseConnectionID([]byte{1, 2, 3}),
@@ -1262,6 +1846,27 @@ var _ = Describe("Connection", func() {
 			Eventually(sent).Should(BeClosed())
 		})
 
+		It("updates the time of the last activity when sending a packet", func() {
+			conn.handshakeConfirmed = true
+			sph.EXPECT().TimeUntilSend().AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
+			sph.EXPECT().ECNMode(true).Return(protocol.ECNNon).AnyTimes()
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			runConn()
+			Expect(conn.LastActivity()).To(BeZero())
+			expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 1337}, []byte("foobar"))
+			packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), conn.version).Return(shortHeaderPacket{}, errNothingToPack).AnyTimes()
+			sent := make(chan struct{})
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(*packetBuffer, uint16, protocol.ECN) { close(sent) })
+			tracer.EXPECT().SentShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			now := time.Now()
+			conn.scheduleSending()
+			Eventually(sent).Should(BeClosed())
+			Expect(conn.LastActivity()).To(BeTemporally("~", now, scaleDuration(50*time.Millisecond)))
+		})
+
 		It("doesn't send packets if there's nothing to send", func() {
 			conn.handshakeConfirmed = true
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
@@ -1295,7 +1900,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 			sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			fc := mocks.NewMockConnectionFlowController(mockCtrl)
 			fc.EXPECT().IsNewlyBlocked().Return(true, protocol.ByteCount(1337))
 			expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 13}, []byte("foobar"))
@@ -1351,7 +1956,7 @@ var _ = Describe("Connection", func() {
 					sph.EXPECT().ECNMode(gomock.Any())
 					p := getCoalescedPacket(123, enc != protocol.Encryption1RTT)
 					packer.EXPECT().MaybePackProbePacket(encLevel, gomock.Any(), conn.version).Return(p, nil)
-					sph.EXPECT().SentPacket(gomock.Any(), protocol.PacketNumber(123), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+					sph.EXPECT().SentPacket(gomock.Any(), protocol.PacketNumber(123), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 					conn.sentPacketHandler = sph
 					runConn()
 					sent := make(chan struct{})
@@ -1374,7 +1979,7 @@ var _ = Describe("Connection", func() {
 					sph.EXPECT().QueueProbePacket(encLevel).Return(false)
 					p := getCoalescedPacket(123, enc != protocol.Encryption1RTT)
 					packer.EXPECT().MaybePackProbePacket(encLevel, gomock.Any(), conn.version).Return(p, nil)
-					sph.EXPECT().SentPacket(gomock.Any(), protocol.PacketNumber(123), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+					sph.EXPECT().SentPacket(gomock.Any(), protocol.PacketNumber(123), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 					runConn()
 					sent := make(chan struct{})
 					sender.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Do(func(*packetBuffer, uint16, protocol.ECN) { close(sent) })
@@ -1393,6 +1998,126 @@ var _ = Describe("Connection", func() {
 		}
 	})
 
+	Context("triggering timers", func() {
+		var sender *MockSender
+
+		BeforeEach(func() {
+			sender = NewMockSender(mockCtrl)
+			sender.EXPECT().WouldBlock().AnyTimes()
+			conn.sendQueue = sender
+			tracer.EXPECT().UpdatedMetrics(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().SetLossTimer(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().LossTimerCanceled().AnyTimes()
+			tracer.EXPECT().DroppedEncryptionLevel(gomock.Any()).AnyTimes()
+			// receiving a Handshake packet validates the client's address
+			Expect(conn.receivedPacketHandler.ReceivedPacket(0, protocol.ECNNon, protocol.EncryptionHandshake, time.Now(), false)).To(Succeed())
+			conn.sentPacketHandler.DropPackets(protocol.EncryptionInitial)
+			conn.sentPacketHandler.DropPackets(protocol.EncryptionHandshake)
+			conn.sentPacketHandler.SetHandshakeConfirmed()
+			conn.handshakeConfirmed = true
+		})
+
+		It("sends PTO probe packets when the PTO timer fires", func() {
+			pn := conn.sentPacketHandler.PopPacketNumber(protocol.Encryption1RTT)
+			conn.sentPacketHandler.SentPacket(time.Now(), pn, protocol.InvalidPacketNumber, nil, []ackhandler.Frame{{Frame: &wire.PingFrame{}}}, protocol.Encryption1RTT, protocol.ECNNon, 1000, false, false)
+			Expect(conn.sentPacketHandler.GetLossDetectionTimeout()).To(BeTemporally(">", time.Now()))
+
+			tracer.EXPECT().LossTimerExpired(logging.TimerTypePTO, protocol.Encryption1RTT)
+			tracer.EXPECT().UpdatedPTOCount(uint32(1))
+			var probes []protocol.PacketNumber
+			packer.EXPECT().MaybePackProbePacket(protocol.Encryption1RTT, gomock.Any(), conn.version).DoAndReturn(func(protocol.EncryptionLevel, protocol.ByteCount, protocol.VersionNumber) (*coalescedPacket, error) {
+				pn := conn.sentPacketHandler.PopPacketNumber(protocol.Encryption1RTT)
+				probes = append(probes, pn)
+				buffer := getPacketBuffer()
+				buffer.Data = append(buffer.Data, []byte("foobar")...)
+				return &coalescedPacket{
+					buffer: buffer,
+					shortHdrPacket: &shortHeaderPacket{
+						PacketNumber: pn,
+						Frames:       []ackhandler.Frame{{Frame: &wire.PingFrame{}}},
+						Length:       6,
+					},
+				}, nil
+			}).Times(2)
+			tracer.EXPECT().SentShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			sender.EXPECT().Send(gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), conn.version).Return(shortHeaderPacket{}, errNothingToPack)
+			Expect(conn.triggerTimerForTest(timerTypeLossDetection)).To(Succeed())
+			Expect(probes).To(HaveLen(2))
+			Expect(probes[0]).To(BeNumerically(">", pn))
+			Expect(probes[1]).To(BeNumerically(">", probes[0]))
+		})
+
+		It("sends an ACK when the ACK timer fires", func() {
+			Expect(conn.triggerTimerForTest(timerTypeAck)).To(MatchError("ACK timer not set"))
+			// The first packet is acknowledged immediately.
+			Expect(conn.receivedPacketHandler.ReceivedPacket(0, protocol.ECNNon, protocol.Encryption1RTT, time.Now(), true)).To(Succeed())
+			Expect(conn.receivedPacketHandler.GetAckFrame(protocol.Encryption1RTT, true)).ToNot(BeNil())
+			// For the second packet, the ACK timer is set.
+			Expect(conn.receivedPacketHandler.ReceivedPacket(1, protocol.ECNNon, protocol.Encryption1RTT, time.Now(), true)).To(Succeed())
+			Expect(conn.receivedPacketHandler.GetAlarmTimeout()).To(BeTemporally(">", time.Now()))
+
+			var ack *wire.AckFrame
+			packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), conn.version).DoAndReturn(func(*packetBuffer, protocol.ByteCount, protocol.VersionNumber) (shortHeaderPacket, error) {
+				ack = conn.receivedPacketHandler.GetAckFrame(protocol.Encryption1RTT, true)
+				return shortHeaderPacket{}, errNothingToPack
+			})
+			Expect(conn.triggerTimerForTest(timerTypeAck)).To(Succeed())
+			Expect(ack).ToNot(BeNil())
+			Expect(ack.LargestAcked()).To(Equal(protocol.PacketNumber(1)))
+			Expect(conn.receivedPacketHandler.GetAlarmTimeout()).To(BeZero())
+		})
+
+		It("declares packets lost when the loss timer fires", func() {
+			now := time.Now()
+			pn1 := conn.sentPacketHandler.PopPacketNumber(protocol.Encryption1RTT)
+			conn.sentPacketHandler.SentPacket(now.Add(-10*time.Millisecond), pn1, protocol.InvalidPacketNumber, nil, []ackhandler.Frame{{Frame: &wire.PingFrame{}}}, protocol.Encryption1RTT, protocol.ECNNon, 1000, false, false)
+			pn2 := conn.sentPacketHandler.PopPacketNumber(protocol.Encryption1RTT)
+			conn.sentPacketHandler.SentPacket(now.Add(-10*time.Millisecond), pn2, protocol.InvalidPacketNumber, nil, []ackhandler.Frame{{Frame: &wire.PingFrame{}}}, protocol.Encryption1RTT, protocol.ECNNon, 1000, false, false)
+			// Acknowledge the second packet. The first packet is not yet lost, but the loss timer is set.
+			tracer.EXPECT().AcknowledgedPacket(gomock.Any(), gomock.Any()).AnyTimes()
+			tracer.EXPECT().UpdatedCongestionState(gomock.Any()).AnyTimes()
+			_, err := conn.sentPacketHandler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: pn2, Largest: pn2}}}, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(conn.sentPacketHandler.GetLossDetectionTimeout()).To(BeTemporally(">", now))
+
+			gomock.InOrder(
+				tracer.EXPECT().LossTimerExpired(logging.TimerTypeACK, protocol.Encryption1RTT),
+				tracer.EXPECT().LostPacket(protocol.Encryption1RTT, pn1, logging.PacketLossTimeThreshold),
+			)
+			packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), conn.version).Return(shortHeaderPacket{}, errNothingToPack)
+			Expect(conn.triggerTimerForTest(timerTypeLossDetection)).To(Succeed())
+			// no packets are outstanding anymore
+			Expect(conn.triggerTimerForTest(timerTypeLossDetection)).To(MatchError("loss detection timer not set"))
+		})
+
+		It("closes the connection when the handshake idle timer fires", func() {
+			conn.handshakeComplete = false
+			Expect(conn.triggerTimerForTest(timerTypeIdle)).To(Succeed())
+			var closeErr closeError
+			Eventually(conn.closeChan).Should(Receive(&closeErr))
+			Expect(closeErr.err).To(MatchError(qerr.ErrIdleTimeout))
+			Expect(closeErr.immediate).To(BeTrue())
+		})
+
+		It("closes the connection when the handshake timer fires", func() {
+			conn.handshakeComplete = false
+			conn.creationTime = time.Now().Add(-time.Hour)
+			Expect(conn.triggerTimerForTest(timerTypeIdle)).To(Succeed())
+			var closeErr closeError
+			Eventually(conn.closeChan).Should(Receive(&closeErr))
+			Expect(closeErr.err).To(MatchError(qerr.ErrHandshakeTimeout))
+		})
+
+		It("closes the connection when the idle timer fires after the handshake completed", func() {
+			conn.idleTimeout = time.Hour
+			Expect(conn.triggerTimerForTest(timerTypeIdle)).To(Succeed())
+			var closeErr closeError
+			Eventually(conn.closeChan).Should(Receive(&closeErr))
+			Expect(closeErr.err).To(MatchError(qerr.ErrIdleTimeout))
+		})
+	})
+
 	Context("packet pacing", func() {
 		var (
 			sph    *mockackhandler.MockSentPacketHandler
@@ -1427,7 +2152,7 @@ var _ = Describe("Connection", func() {
 		})
 
 		It("sends multiple packets one by one immediately", func() {
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).Times(2)
 			sph.EXPECT().ECNMode(gomock.Any()).Times(2)
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendPacingLimited)
@@ -1453,7 +2178,7 @@ var _ = Describe("Connection", func() {
 
 		It("sends multiple packets one by one immediately, with GSO", func() {
 			enableGSO()
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
 			sph.EXPECT().ECNMode(true).Return(protocol.ECT1).Times(4)
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).Times(3)
 			payload1 := make([]byte, conn.mtuDiscoverer.CurrentSize())
@@ -1477,9 +2202,42 @@ var _ = Describe("Connection", func() {
 			time.Sleep(50 * time.Millisecond) // make sure that only 2 packets are sent
 		})
 
+		It("limits the number of packets in a batch, with GSO", func() {
+			enableGSO()
+			conn.config.MaxGSOBatchSize = 2
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			sph.EXPECT().ECNMode(true).Return(protocol.ECT1).Times(5)
+			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).Times(4)
+			payload1 := make([]byte, conn.mtuDiscoverer.CurrentSize())
+			rand.Read(payload1)
+			payload2 := make([]byte, conn.mtuDiscoverer.CurrentSize())
+			rand.Read(payload2)
+			payload3 := make([]byte, conn.mtuDiscoverer.CurrentSize())
+			rand.Read(payload3)
+			expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 10}, payload1)
+			expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 11}, payload2)
+			expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 12}, payload3)
+			packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), gomock.Any()).Return(shortHeaderPacket{}, errNothingToPack)
+			sender.EXPECT().WouldBlock().AnyTimes()
+			sender.EXPECT().Send(gomock.Any(), uint16(conn.mtuDiscoverer.CurrentSize()), gomock.Any()).Do(func(b *packetBuffer, _ uint16, _ protocol.ECN) {
+				Expect(b.Data).To(Equal(append(payload1, payload2...)))
+			})
+			sender.EXPECT().Send(gomock.Any(), uint16(conn.mtuDiscoverer.CurrentSize()), gomock.Any()).Do(func(b *packetBuffer, _ uint16, _ protocol.ECN) {
+				Expect(b.Data).To(Equal(payload3))
+			})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				conn.run()
+			}()
+			conn.scheduleSending()
+			time.Sleep(50 * time.Millisecond) // make sure that only 3 packets are sent
+		})
+
 		It("stops appending packets when a smaller packet is packed, with GSO", func() {
 			enableGSO()
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).Times(3)
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendNone)
 			sph.EXPECT().ECNMode(true).Times(4)
@@ -1511,7 +2269,7 @@ var _ = Describe("Connection", func() {
 
 		It("stops appending packets when the ECN marking changes, with GSO", func() {
 			enableGSO()
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).Times(3)
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendNone)
 			sph.EXPECT().ECNMode(true).Return(protocol.ECT1).Times(2)
@@ -1543,7 +2301,7 @@ var _ = Describe("Connection", func() {
 		})
 
 		It("sends multiple packets, when the pacer allows immediate sending", func() {
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).Times(2)
 			sph.EXPECT().ECNMode(gomock.Any()).Times(2)
 			expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 10}, []byte("packet10"))
@@ -1561,7 +2319,7 @@ var _ = Describe("Connection", func() {
 		})
 
 		It("allows an ACK to be sent when pacing limited", func() {
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour))
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendPacingLimited)
 			sph.EXPECT().ECNMode(gomock.Any())
@@ -1582,7 +2340,7 @@ var _ = Describe("Connection", func() {
 		// when becoming congestion limited, at some point the SendMode will change from SendAny to SendAck
 		// we shouldn't send the ACK in the same run
 		It("doesn't send an ACK right after becoming congestion limited", func() {
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny)
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAck)
 			sph.EXPECT().ECNMode(gomock.Any()).Times(2)
@@ -1605,13 +2363,13 @@ var _ = Describe("Connection", func() {
 				sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny),
 				sph.EXPECT().ECNMode(gomock.Any()),
 				expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 100}, []byte("packet100")),
-				sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()),
+				sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()),
 				sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendPacingLimited),
 				sph.EXPECT().TimeUntilSend().Return(time.Now().Add(pacingDelay)),
 				sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny),
 				sph.EXPECT().ECNMode(gomock.Any()),
 				expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 101}, []byte("packet101")),
-				sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()),
+				sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()),
 				sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendPacingLimited),
 				sph.EXPECT().TimeUntilSend().Return(time.Now().Add(time.Hour)),
 			)
@@ -1631,7 +2389,7 @@ var _ = Describe("Connection", func() {
 		})
 
 		It("sends multiple packets at once", func() {
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).Times(3)
 			sph.EXPECT().ECNMode(gomock.Any()).Times(3)
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendPacingLimited)
@@ -1672,7 +2430,7 @@ var _ = Describe("Connection", func() {
 
 				written := make(chan struct{})
 				sender.EXPECT().WouldBlock().AnyTimes()
-				sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+				sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 				sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 				sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
 				expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 1000}, []byte("packet1000"))
@@ -1694,7 +2452,7 @@ var _ = Describe("Connection", func() {
 
 			written := make(chan struct{})
 			sender.EXPECT().WouldBlock().AnyTimes()
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(func(time.Time, protocol.PacketNumber, protocol.PacketNumber, []ackhandler.StreamFrame, []ackhandler.Frame, protocol.EncryptionLevel, protocol.ECN, protocol.ByteCount, bool) {
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(func(time.Time, protocol.PacketNumber, protocol.PacketNumber, []ackhandler.StreamFrame, []ackhandler.Frame, protocol.EncryptionLevel, protocol.ECN, protocol.ByteCount, bool, bool) {
 				sph.EXPECT().ReceivedBytes(gomock.Any())
 				conn.handlePacket(receivedPacket{buffer: getPacketBuffer()})
 			})
@@ -1711,7 +2469,7 @@ var _ = Describe("Connection", func() {
 		})
 
 		It("stops sending when the send queue is full", func() {
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny)
 			sph.EXPECT().ECNMode(gomock.Any())
 			expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 1000}, []byte("packet1000"))
@@ -1732,7 +2490,7 @@ var _ = Describe("Connection", func() {
 			time.Sleep(scaleDuration(50 * time.Millisecond))
 
 			// now make room in the send queue
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 			sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
 			sender.EXPECT().WouldBlock().AnyTimes()
@@ -1767,7 +2525,7 @@ var _ = Describe("Connection", func() {
 			mtuDiscoverer := NewMockMTUDiscoverer(mockCtrl)
 			conn.mtuDiscoverer = mtuDiscoverer
 			conn.config.DisablePathMTUDiscovery = false
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny)
 			sph.EXPECT().ECNMode(true)
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendNone)
@@ -1822,7 +2580,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 			sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
 
-			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			conn.sentPacketHandler = sph
 			expectAppendPacket(packer, shortHeaderPacket{PacketNumber: 1}, []byte("packet1"))
 			packer.EXPECT().AppendPacket(gomock.Any(), gomock.Any(), conn.version).Return(shortHeaderPacket{}, errNothingToPack)
@@ -1850,7 +2608,7 @@ var _ = Describe("Connection", func() {
 			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendAny).AnyTimes()
 			sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
-			sph.EXPECT().SentPacket(gomock.Any(), protocol.PacketNumber(1234), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), protocol.PacketNumber(1234), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 			conn.sentPacketHandler = sph
 			rph := mockackhandler.NewMockReceivedPacketHandler(mockCtrl)
 			rph.EXPECT().GetAlarmTimeout().Return(time.Now().Add(10 * time.Millisecond))
@@ -1904,8 +2662,8 @@ var _ = Describe("Connection", func() {
 		sph.EXPECT().ECNMode(false).Return(protocol.ECT1).AnyTimes()
 		sph.EXPECT().TimeUntilSend().Return(time.Now()).AnyTimes()
 		gomock.InOrder(
-			sph.EXPECT().SentPacket(gomock.Any(), protocol.PacketNumber(13), gomock.Any(), gomock.Any(), gomock.Any(), protocol.EncryptionInitial, protocol.ECT1, protocol.ByteCount(123), gomock.Any()),
-			sph.EXPECT().SentPacket(gomock.Any(), protocol.PacketNumber(37), gomock.Any(), gomock.Any(), gomock.Any(), protocol.EncryptionHandshake, protocol.ECT1, protocol.ByteCount(1234), gomock.Any()),
+			sph.EXPECT().SentPacket(gomock.Any(), protocol.PacketNumber(13), gomock.Any(), gomock.Any(), gomock.Any(), protocol.EncryptionInitial, protocol.ECT1, protocol.ByteCount(123), gomock.Any(), gomock.Any()),
+			sph.EXPECT().SentPacket(gomock.Any(), protocol.PacketNumber(37), gomock.Any(), gomock.Any(), gomock.Any(), protocol.EncryptionHandshake, protocol.ECT1, protocol.ByteCount(1234), gomock.Any(), gomock.Any()),
 		)
 		gomock.InOrder(
 			tracer.EXPECT().SentLongHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Do(func(hdr *wire.ExtendedHeader, _ protocol.ByteCount, _ logging.ECN, _ *wire.AckFrame, _ []logging.Frame) {
@@ -2022,7 +2780,7 @@ var _ = Describe("Connection", func() {
 		sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
 		sph.EXPECT().TimeUntilSend().AnyTimes()
 		sph.EXPECT().SetHandshakeConfirmed()
-		sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+		sph.EXPECT().SentPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 		mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
 		tracer.EXPECT().SentShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
 		tracer.EXPECT().ChoseALPN(gomock.Any())
@@ -2235,6 +2993,8 @@ var _ = Describe("Connection", func() {
 		})
 
 		It("times out due to no network activity", func() {
+			connErrChan := make(chan error, 1)
+			conn.config.OnConnectionError = func(e error) { connErrChan <- e }
 			connRunner.EXPECT().Remove(gomock.Any()).Times(2)
 			conn.lastPacketReceivedTime = time.Now().Add(-time.Hour)
 			done := make(chan struct{})
@@ -2257,6 +3017,66 @@ var _ = Describe("Connection", func() {
 				close(done)
 			}()
 			Eventually(done).Should(BeClosed())
+			Expect(connErrChan).To(Receive(MatchError(qerr.ErrIdleTimeout)))
+		})
+
+		It("closes the connection after the maximum connection duration", func() {
+			conn.handshakeComplete = true
+			conn.config.MaxConnectionDuration = time.Minute
+			conn.creationTime = time.Now().Add(-time.Hour)
+			connErrChan := make(chan error, 1)
+			conn.config.OnConnectionError = func(e error) { connErrChan <- e }
+			packer.EXPECT().PackCoalescedPacket(false, gomock.Any(), conn.version).AnyTimes()
+			// the peer is sent an application error with error code 0
+			packer.EXPECT().PackApplicationClose(&qerr.ApplicationError{
+				ErrorCode:    0,
+				ErrorMessage: "maximum connection duration reached",
+			}, gomock.Any(), conn.version).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(&MaxConnectionDurationError{}),
+				tracer.EXPECT().Close(),
+			)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				err := conn.run()
+				Expect(err).To(MatchError(qerr.ErrMaxConnectionDuration))
+				var applicationErr *ApplicationError
+				Expect(errors.As(err, &applicationErr)).To(BeFalse())
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+			// unlike closing the connection using CloseWithError, this is reported as a connection error
+			Expect(connErrChan).To(Receive(MatchError(qerr.ErrMaxConnectionDuration)))
+		})
+
+		It("arms the timer for the maximum connection duration", func() {
+			conn.handshakeComplete = true
+			conn.config.MaxIdleTimeout = time.Hour
+			conn.config.MaxConnectionDuration = scaleDuration(50 * time.Millisecond)
+			conn.creationTime = time.Now()
+			packer.EXPECT().PackCoalescedPacket(false, gomock.Any(), conn.version).AnyTimes()
+			packer.EXPECT().PackApplicationClose(gomock.Any(), gomock.Any(), conn.version).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			expectReplaceWithClosed()
+			cryptoSetup.EXPECT().Close()
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				conn.run()
+				close(done)
+			}()
+			Consistently(done, scaleDuration(25*time.Millisecond)).ShouldNot(BeClosed())
+			Eventually(done).Should(BeClosed())
 		})
 
 		It("times out due to non-completed handshake", func() {
@@ -2285,6 +3105,109 @@ var _ = Describe("Connection", func() {
 			Eventually(done).Should(BeClosed())
 		})
 
+		It("closes the connection if packets are received, but the handshake makes no progress", func() {
+			conn.handshakeComplete = false
+			now := time.Now()
+			conn.creationTime = now.Add(-conn.config.handshakeNoProgressTimeout() - time.Second)
+			conn.lastHandshakeProgressTime = now.Add(-conn.config.handshakeNoProgressTimeout() - time.Second)
+			// the peer is still sending packets (e.g. ACKs), so this is not an idle timeout
+			conn.lastPacketReceivedTime = now.Add(-time.Second)
+			connRunner.EXPECT().Remove(gomock.Any()).Times(2)
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(gomock.Any()).Do(func(e error) {
+					Expect(e).To(MatchError(&HandshakeNoProgressError{}))
+				}),
+				tracer.EXPECT().Close(),
+			)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				err := conn.run()
+				nerr, ok := err.(net.Error)
+				Expect(ok).To(BeTrue())
+				Expect(nerr.Timeout()).To(BeTrue())
+				Expect(err).To(MatchError(qerr.ErrHandshakeNoProgress))
+				close(done)
+			}()
+			Eventually(done).Should(BeClosed())
+		})
+
+		It("closes the connection after the no-progress timeout, if the peer keeps sending packets", func() {
+			unpacker := NewMockUnpacker(mockCtrl)
+			conn.unpacker = unpacker
+			conn.handshakeComplete = false
+			conn.config.HandshakeIdleTimeout = scaleDuration(50 * time.Millisecond)
+			start := time.Now()
+			conn.creationTime = start
+			conn.lastHandshakeProgressTime = start
+			conn.lastPacketReceivedTime = start
+			// The peer sends PING frames, which are acknowledged, but never advances the handshake.
+			var pn protocol.PacketNumber
+			unpacker.EXPECT().UnpackLongHeader(gomock.Any(), gomock.Any(), gomock.Any(), conn.version).DoAndReturn(func(hdr *wire.Header, _ time.Time, _ []byte, _ protocol.VersionNumber) (*unpackedPacket, error) {
+				pn++
+				return &unpackedPacket{
+					encryptionLevel: protocol.EncryptionHandshake,
+					hdr:             &wire.ExtendedHeader{Header: *hdr, PacketNumber: pn, PacketNumberLen: protocol.PacketNumberLen1},
+					data:            []byte{0x1}, // PING frame
+				}, nil
+			}).AnyTimes()
+			packer.EXPECT().PackCoalescedPacket(false, gomock.Any(), conn.version).AnyTimes()
+			tracer.EXPECT().StartedConnection(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).MaxTimes(1)
+			tracer.EXPECT().ReceivedLongHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+			// receiving Handshake packets drops the Initial keys
+			cryptoSetup.EXPECT().DiscardInitialKeys().AnyTimes()
+			tracer.EXPECT().DroppedEncryptionLevel(protocol.EncryptionInitial).AnyTimes()
+			connRunner.EXPECT().Remove(gomock.Any()).Times(2)
+			cryptoSetup.EXPECT().Close()
+			gomock.InOrder(
+				tracer.EXPECT().ClosedConnection(&HandshakeNoProgressError{}),
+				tracer.EXPECT().Close(),
+			)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				Expect(conn.run()).To(MatchError(qerr.ErrHandshakeNoProgress))
+				close(done)
+			}()
+			hdr := &wire.ExtendedHeader{
+				Header: wire.Header{
+					Type:             protocol.PacketTypeHandshake,
+					DestConnectionID: srcConnID,
+					SrcConnectionID:  destConnID,
+					Length:           2,
+					Version:          conn.version,
+				},
+				PacketNumberLen: protocol.PacketNumberLen1,
+			}
+			b, err := hdr.Append(nil, conn.version)
+			Expect(err).ToNot(HaveOccurred())
+			ticker := time.NewTicker(conn.config.HandshakeIdleTimeout / 10)
+			defer ticker.Stop()
+		loop:
+			for {
+				select {
+				case <-done:
+					break loop
+				case <-ticker.C:
+					conn.handlePacket(receivedPacket{
+						data:    append(append([]byte{}, b...), 0),
+						buffer:  getPacketBuffer(),
+						rcvTime: time.Now(),
+					})
+				}
+			}
+			// Packets were received all the time, so neither the idle timeout nor the handshake timeout fired.
+			Expect(time.Since(start)).To(And(
+				BeNumerically(">=", conn.config.handshakeNoProgressTimeout()),
+				BeNumerically("<", conn.config.handshakeTimeout()),
+			))
+		})
+
 		It("does not use the idle timeout before the handshake complete", func() {
 			conn.handshakeComplete = false
 			conn.config.HandshakeIdleTimeout = 9999 * time.Second
@@ -2383,6 +3306,8 @@ var _ = Describe("Connection", func() {
 				close(done)
 			}()
 			Eventually(done).Should(BeClosed())
+			var idleErr *IdleTimeoutError
+			Expect(errors.As(context.Cause(conn.Context()), &idleErr)).To(BeTrue())
 		})
 
 		It("doesn't time out when it just sent a packet", func() {
@@ -2509,6 +3434,27 @@ var _ = Describe("Connection", func() {
 	It("returns the remote address", func() {
 		Expect(conn.RemoteAddr()).To(Equal(remoteAddr))
 	})
+
+	It("reports the max packet size, as determined by Path MTU Discovery", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		conn.sentPacketHandler = sph
+		initialSize := conn.MaxPacketSize()
+		Expect(initialSize).To(Equal(int(getMaxPacketSize(remoteAddr))))
+		conn.mtuDiscoverer.Start(protocol.MaxPacketBufferSize)
+		ping, size := conn.mtuDiscoverer.GetPing()
+		Expect(int(size)).To(BeNumerically(">", initialSize))
+		sph.EXPECT().SetMaxDatagramSize(size)
+		ping.Handler.OnAcked(ping.Frame)
+		Expect(conn.MaxPacketSize()).To(Equal(int(size)))
+	})
+
+	It("switches the congestion controller", func() {
+		sph := mockackhandler.NewMockSentPacketHandler(mockCtrl)
+		conn.sentPacketHandler = sph
+		cc := mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+		sph.EXPECT().SetCongestionController(cc)
+		conn.setCongestionController(cc)
+	})
 })
 
 var _ = Describe("Client Connection", func() {
@@ -2575,6 +3521,7 @@ var _ = Describe("Client Connection", func() {
 			protocol.Version1,
 		).(*connection)
 		packer = NewMockPacker(mockCtrl)
+		packer.EXPECT().SetSpinBit(gomock.Any()).AnyTimes()
 		conn.packer = packer
 		cryptoSetup = mocks.NewMockCryptoSetup(mockCtrl)
 		conn.cryptoStreamHandler = cryptoSetup
@@ -2701,6 +3648,159 @@ var _ = Describe("Client Connection", func() {
 		Eventually(areConnsRunning).Should(BeFalse())
 	})
 
+	It("refuses to probe a path before the handshake completed", func() {
+		_, err := conn.ProbePath(context.Background(), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).To(MatchError("can't probe a path before the handshake completed"))
+	})
+
+	It("refuses to probe a path if the server disabled active connection migration", func() {
+		conn.peerParams = &wire.TransportParameters{DisableActiveMigration: true}
+		conn.handshakeCtxCancel()
+		_, err := conn.ProbePath(context.Background(), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		Expect(err).To(MatchError("peer disabled active connection migration"))
+	})
+
+	Context("probing paths", func() {
+		var (
+			peer      *net.UDPConn
+			localAddr *net.UDPAddr
+			sph       *mockackhandler.MockSentPacketHandler
+			errChan   chan error
+
+			handshakeConfirmed bool
+		)
+		probeConnID := protocol.ParseConnectionID([]byte{0xde, 0xca, 0xfb, 0xad})
+
+		BeforeEach(func() {
+			handshakeConfirmed = true
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			peer, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			localAddr = &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+			mconn = NewMockSendConn(mockCtrl)
+			mconn.EXPECT().RemoteAddr().Return(peer.LocalAddr()).AnyTimes()
+			mconn.EXPECT().LocalAddr().Return(localAddr).AnyTimes()
+			mconn.EXPECT().capabilities().AnyTimes()
+			conn.conn = mconn
+			sph = mockackhandler.NewMockSentPacketHandler(mockCtrl)
+			sph.EXPECT().ReceivedBytes(gomock.Any()).AnyTimes()
+			sph.EXPECT().GetLossDetectionTimeout().AnyTimes()
+			sph.EXPECT().SendMode(gomock.Any()).Return(ackhandler.SendNone).AnyTimes()
+			sph.EXPECT().ECNMode(gomock.Any()).AnyTimes()
+			conn.sentPacketHandler = sph
+			conn.peerParams = &wire.TransportParameters{}
+			conn.idleTimeout = time.Minute
+			conn.lastPacketReceivedTime = time.Now()
+			conn.handshakeComplete = true
+			conn.handshakeConfirmed = handshakeConfirmed
+			conn.handshakeCtxCancel()
+			Expect(conn.connIDManager.Add(&wire.NewConnectionIDFrame{SequenceNumber: 1, ConnectionID: probeConnID})).To(Succeed())
+
+			errChan = make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				cryptoSetup.EXPECT().StartHandshake().MaxTimes(1)
+				cryptoSetup.EXPECT().NextEvent().Return(handshake.Event{Kind: handshake.EventNoEvent})
+				errChan <- conn.run()
+			}()
+		})
+
+		AfterEach(func() {
+			packer.EXPECT().PackApplicationClose(gomock.Any(), gomock.Any(), conn.version).Return(&coalescedPacket{buffer: getPacketBuffer()}, nil)
+			cryptoSetup.EXPECT().Close()
+			connRunner.EXPECT().ReplaceWithClosed([]protocol.ConnectionID{srcConnID}, gomock.Any(), gomock.Any())
+			mconn.EXPECT().Write(gomock.Any(), gomock.Any(), gomock.Any())
+			tracer.EXPECT().ClosedConnection(gomock.Any())
+			tracer.EXPECT().Close()
+			conn.shutdown()
+			Eventually(errChan).Should(Receive())
+			peer.Close()
+		})
+
+		expectPathProbePacket := func(challenge chan<- [8]byte) {
+			packer.EXPECT().PackPathProbePacket(probeConnID, gomock.Any(), conn.version).DoAndReturn(
+				func(_ protocol.ConnectionID, f ackhandler.Frame, _ protocol.VersionNumber) (shortHeaderPacket, *packetBuffer, error) {
+					challenge <- f.Frame.(*wire.PathChallengeFrame).Data
+					buf := getPacketBuffer()
+					buf.Data = append(buf.Data, make([]byte, protocol.MinInitialPacketSize)...)
+					return shortHeaderPacket{
+						PacketNumber: 10,
+						Frames:       []ackhandler.Frame{f},
+						DestConnID:   probeConnID,
+						Length:       protocol.MinInitialPacketSize,
+					}, buf, nil
+				},
+			)
+			tracer.EXPECT().SentShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+			sph.EXPECT().SentPacket(gomock.Any(), protocol.PacketNumber(10), protocol.InvalidPacketNumber, gomock.Any(), gomock.Any(), protocol.Encryption1RTT, protocol.ECNNon, protocol.ByteCount(protocol.MinInitialPacketSize), false, true)
+		}
+
+		It("probes a path, without switching to it", func() {
+			challenge := make(chan [8]byte, 1)
+			expectPathProbePacket(challenge)
+			unpacker := NewMockUnpacker(mockCtrl)
+			conn.unpacker = unpacker
+			unpacker.EXPECT().UnpackShortHeader(gomock.Any(), gomock.Any()).DoAndReturn(func(time.Time, []byte) (protocol.PacketNumber, protocol.PacketNumberLen, protocol.KeyPhaseBit, []byte, error) {
+				b, err := (&wire.PathResponseFrame{Data: <-challenge}).Append(nil, conn.version)
+				Expect(err).ToNot(HaveOccurred())
+				return 7, protocol.PacketNumberLen2, protocol.KeyPhaseZero, b, nil
+			})
+			tracer.EXPECT().ReceivedShortHeaderPacket(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any())
+
+			// the peer responds on the path that is being probed
+			go func() {
+				defer GinkgoRecover()
+				b := make([]byte, protocol.MaxPacketBufferSize)
+				n, addr, err := peer.ReadFrom(b)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(protocol.MinInitialPacketSize))
+				Expect(addr).ToNot(Equal(localAddr))
+				_, err = peer.WriteTo(append([]byte{0x40}, append(srcConnID.Bytes(), []byte("foobar")...)...), addr)
+				Expect(err).ToNot(HaveOccurred())
+			}()
+
+			validated, err := conn.ProbePath(context.Background(), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(validated).To(BeTrue())
+			// the connection still uses the original path
+			Expect(conn.LocalAddr()).To(Equal(localAddr))
+			Expect(conn.RemoteAddr()).To(Equal(peer.LocalAddr()))
+			// the probe packet doesn't count towards the packets sent with the current connection ID
+			Expect(conn.connIDManager.packetsSinceLastChange).To(BeZero())
+		})
+
+		It("fails to validate the path if the peer doesn't respond", func() {
+			// RFC 9000, section 8.2.4: three times the PTO (using the initial RTT)
+			timeout := 3 * (&utils.RTTStats{}).PTO(true)
+			expectPathProbePacket(make(chan [8]byte, 1))
+			start := time.Now()
+			validated, err := conn.ProbePath(context.Background(), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(validated).To(BeFalse())
+			Expect(time.Since(start)).To(BeNumerically(">=", timeout))
+		})
+
+		Context("before the handshake is confirmed", func() {
+			BeforeEach(func() {
+				handshakeConfirmed = false
+			})
+
+			It("stops waiting when the context is canceled", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+				defer cancel()
+				validated, err := conn.ProbePath(ctx, &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+				Expect(err).To(MatchError(context.DeadlineExceeded))
+				Expect(validated).To(BeFalse())
+				conn.pathProbeMutex.Lock()
+				defer conn.pathProbeMutex.Unlock()
+				Expect(conn.queuedPathProbes).To(BeEmpty())
+			})
+		})
+	})
+
 	Context("handling tokens", func() {
 		var mockTokenStore *MockTokenStore
 
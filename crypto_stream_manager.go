@@ -35,7 +35,9 @@ func newCryptoStreamManager(
 	}
 }
 
-func (m *cryptoStreamManager) HandleCryptoFrame(frame *wire.CryptoFrame, encLevel protocol.EncryptionLevel) error {
+// HandleCryptoFrame handles a CRYPTO frame.
+// It returns true if at least one new handshake message was passed to the crypto handler.
+func (m *cryptoStreamManager) HandleCryptoFrame(frame *wire.CryptoFrame, encLevel protocol.EncryptionLevel) (bool /* processed new message */, error) {
 	var str cryptoStream
 	//nolint:exhaustive // CRYPTO frames cannot be sent in 0-RTT packets.
 	switch encLevel {
@@ -46,19 +48,21 @@ func (m *cryptoStreamManager) HandleCryptoFrame(frame *wire.CryptoFrame, encLeve
 	case protocol.Encryption1RTT:
 		str = m.oneRTTStream
 	default:
-		return fmt.Errorf("received CRYPTO frame with unexpected encryption level: %s", encLevel)
+		return false, fmt.Errorf("received CRYPTO frame with unexpected encryption level: %s", encLevel)
 	}
 	if err := str.HandleCryptoFrame(frame); err != nil {
-		return err
+		return false, err
 	}
+	var processed bool
 	for {
 		data := str.GetCryptoData()
 		if data == nil {
-			return nil
+			return processed, nil
 		}
 		if err := m.cryptoHandler.HandleMessage(data, encLevel); err != nil {
-			return err
+			return processed, err
 		}
+		processed = true
 	}
 }
 
@@ -32,7 +32,9 @@ var _ = Describe("Crypto Stream Manager", func() {
 		initialStream.EXPECT().GetCryptoData().Return([]byte("foobar"))
 		initialStream.EXPECT().GetCryptoData()
 		cs.EXPECT().HandleMessage([]byte("foobar"), protocol.EncryptionInitial)
-		Expect(csm.HandleCryptoFrame(cf, protocol.EncryptionInitial)).To(Succeed())
+		processed, err := csm.HandleCryptoFrame(cf, protocol.EncryptionInitial)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(processed).To(BeTrue())
 	})
 
 	It("passes messages to the handshake stream", func() {
@@ -41,7 +43,9 @@ var _ = Describe("Crypto Stream Manager", func() {
 		handshakeStream.EXPECT().GetCryptoData().Return([]byte("foobar"))
 		handshakeStream.EXPECT().GetCryptoData()
 		cs.EXPECT().HandleMessage([]byte("foobar"), protocol.EncryptionHandshake)
-		Expect(csm.HandleCryptoFrame(cf, protocol.EncryptionHandshake)).To(Succeed())
+		processed, err := csm.HandleCryptoFrame(cf, protocol.EncryptionHandshake)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(processed).To(BeTrue())
 	})
 
 	It("passes messages to the 1-RTT stream", func() {
@@ -50,7 +54,9 @@ var _ = Describe("Crypto Stream Manager", func() {
 		oneRTTStream.EXPECT().GetCryptoData().Return([]byte("foobar"))
 		oneRTTStream.EXPECT().GetCryptoData()
 		cs.EXPECT().HandleMessage([]byte("foobar"), protocol.Encryption1RTT)
-		Expect(csm.HandleCryptoFrame(cf, protocol.Encryption1RTT)).To(Succeed())
+		processed, err := csm.HandleCryptoFrame(cf, protocol.Encryption1RTT)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(processed).To(BeTrue())
 	})
 
 	It("doesn't call the message handler, if there's no message", func() {
@@ -58,7 +64,9 @@ var _ = Describe("Crypto Stream Manager", func() {
 		handshakeStream.EXPECT().HandleCryptoFrame(cf)
 		handshakeStream.EXPECT().GetCryptoData() // don't return any data to handle
 		// don't EXPECT any calls to HandleMessage()
-		Expect(csm.HandleCryptoFrame(cf, protocol.EncryptionHandshake)).To(Succeed())
+		processed, err := csm.HandleCryptoFrame(cf, protocol.EncryptionHandshake)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(processed).To(BeFalse())
 	})
 
 	It("processes all messages", func() {
@@ -69,11 +77,13 @@ var _ = Describe("Crypto Stream Manager", func() {
 		handshakeStream.EXPECT().GetCryptoData()
 		cs.EXPECT().HandleMessage([]byte("foo"), protocol.EncryptionHandshake)
 		cs.EXPECT().HandleMessage([]byte("bar"), protocol.EncryptionHandshake)
-		Expect(csm.HandleCryptoFrame(cf, protocol.EncryptionHandshake)).To(Succeed())
+		processed, err := csm.HandleCryptoFrame(cf, protocol.EncryptionHandshake)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(processed).To(BeTrue())
 	})
 
 	It("errors for unknown encryption levels", func() {
-		err := csm.HandleCryptoFrame(&wire.CryptoFrame{}, 42)
+		_, err := csm.HandleCryptoFrame(&wire.CryptoFrame{}, 42)
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("received CRYPTO frame with unexpected encryption level"))
 	})
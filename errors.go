@@ -7,12 +7,14 @@ import (
 )
 
 type (
-	TransportError          = qerr.TransportError
-	ApplicationError        = qerr.ApplicationError
-	VersionNegotiationError = qerr.VersionNegotiationError
-	StatelessResetError     = qerr.StatelessResetError
-	IdleTimeoutError        = qerr.IdleTimeoutError
-	HandshakeTimeoutError   = qerr.HandshakeTimeoutError
+	TransportError             = qerr.TransportError
+	ApplicationError           = qerr.ApplicationError
+	VersionNegotiationError    = qerr.VersionNegotiationError
+	StatelessResetError        = qerr.StatelessResetError
+	IdleTimeoutError           = qerr.IdleTimeoutError
+	HandshakeTimeoutError      = qerr.HandshakeTimeoutError
+	HandshakeNoProgressError   = qerr.HandshakeNoProgressError
+	MaxConnectionDurationError = qerr.MaxConnectionDurationError
 )
 
 type (
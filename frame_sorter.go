@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/qerr"
 	list "github.com/quic-go/quic-go/internal/utils/linkedlist"
 )
 
@@ -169,8 +170,12 @@ func (s *frameSorter) push(data []byte, offset protocol.ByteCount, doneCb func()
 		}
 	}
 
+	// A peer could send maximally spread out data, in order to make us track a large number of gaps.
 	if s.gaps.Len() > protocol.MaxStreamFrameSorterGaps {
-		return errors.New("too many gaps in received data")
+		return &qerr.TransportError{
+			ErrorCode:    qerr.FlowControlError,
+			ErrorMessage: "too many gaps in received data",
+		}
 	}
 
 	s.queue[start] = frameSorterEntry{Data: data, DoneCb: doneCb}
@@ -9,6 +9,7 @@ import (
 	"golang.org/x/exp/rand"
 
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/qerr"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -1380,7 +1381,41 @@ var _ = Describe("frame sorter", func() {
 				}
 				Expect(s.gaps.Len()).To(Equal(protocol.MaxStreamFrameSorterGaps))
 				err := s.Push([]byte("foobar"), protocol.ByteCount(protocol.MaxStreamFrameSorterGaps*7)+100, nil)
-				Expect(err).To(MatchError("too many gaps in received data"))
+				Expect(err).To(MatchError(&qerr.TransportError{
+					ErrorCode:    qerr.FlowControlError,
+					ErrorMessage: "too many gaps in received data",
+				}))
+			})
+
+			It("bounds the number of gaps when receiving maximally fragmented data", func() {
+				// every other byte
+				var err error
+				var i int
+				for i = 0; i < 10*protocol.MaxStreamFrameSorterGaps; i++ {
+					if err = s.Push([]byte{'f'}, protocol.ByteCount(2*i+1), nil); err != nil {
+						break
+					}
+				}
+				Expect(err).To(HaveOccurred())
+				Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FlowControlError))
+				// the gap at the beginning of the stream counts as well
+				Expect(i).To(Equal(protocol.MaxStreamFrameSorterGaps - 1))
+				Expect(s.queue).To(HaveLen(protocol.MaxStreamFrameSorterGaps - 1))
+			})
+
+			It("doesn't count gaps that were filled", func() {
+				const num = protocol.MaxStreamFrameSorterGaps - 1
+				for i := 0; i < num; i++ {
+					Expect(s.Push([]byte{'f'}, protocol.ByteCount(2*i+1), nil)).To(Succeed())
+				}
+				// fill all the gaps
+				for i := 0; i < num; i++ {
+					Expect(s.Push([]byte{'b'}, protocol.ByteCount(2*i), nil)).To(Succeed())
+				}
+				Expect(s.gaps.Len()).To(Equal(1))
+				for i := 0; i < num; i++ {
+					Expect(s.Push([]byte{'f'}, protocol.ByteCount(2*num+2*i+1), nil)).To(Succeed())
+				}
 			})
 		})
 	})
@@ -18,6 +18,7 @@ type framer interface {
 	AppendControlFrames([]ackhandler.Frame, protocol.ByteCount, protocol.VersionNumber) ([]ackhandler.Frame, protocol.ByteCount)
 
 	AddActiveStream(protocol.StreamID)
+	AddStreamWithRetransmission(protocol.StreamID)
 	AppendStreamFrames([]ackhandler.StreamFrame, protocol.ByteCount, protocol.VersionNumber) ([]ackhandler.StreamFrame, protocol.ByteCount)
 
 	Handle0RTTRejection() error
@@ -32,6 +33,10 @@ type framerI struct {
 
 	activeStreams map[protocol.StreamID]struct{}
 	streamQueue   ringbuffer.RingBuffer[protocol.StreamID]
+	// Streams that have lost STREAM frames queued for retransmission.
+	// These are served before any new data is sent on other streams.
+	streamsWithRetransmissions map[protocol.StreamID]struct{}
+	retransmissionQueue        ringbuffer.RingBuffer[protocol.StreamID]
 
 	controlFrameMutex sync.Mutex
 	controlFrames     []wire.Frame
@@ -42,8 +47,9 @@ var _ framer = &framerI{}
 
 func newFramer(streamGetter streamGetter) framer {
 	return &framerI{
-		streamGetter:  streamGetter,
-		activeStreams: make(map[protocol.StreamID]struct{}),
+		streamGetter:               streamGetter,
+		activeStreams:              make(map[protocol.StreamID]struct{}),
+		streamsWithRetransmissions: make(map[protocol.StreamID]struct{}),
 	}
 }
 
@@ -114,11 +120,53 @@ func (f *framerI) AddActiveStream(id protocol.StreamID) {
 	f.mutex.Unlock()
 }
 
+// AddStreamWithRetransmission marks a stream as having lost STREAM frames.
+// The stream is also added to the active streams, such that it can continue sending new data
+// once all retransmissions have been sent.
+func (f *framerI) AddStreamWithRetransmission(id protocol.StreamID) {
+	f.mutex.Lock()
+	if _, ok := f.streamsWithRetransmissions[id]; !ok {
+		f.retransmissionQueue.PushBack(id)
+		f.streamsWithRetransmissions[id] = struct{}{}
+	}
+	if _, ok := f.activeStreams[id]; !ok {
+		f.streamQueue.PushBack(id)
+		f.activeStreams[id] = struct{}{}
+	}
+	f.mutex.Unlock()
+}
+
 func (f *framerI) AppendStreamFrames(frames []ackhandler.StreamFrame, maxLen protocol.ByteCount, v protocol.VersionNumber) ([]ackhandler.StreamFrame, protocol.ByteCount) {
 	startLen := len(frames)
 	var length protocol.ByteCount
 	f.mutex.Lock()
-	// pop STREAM frames, until less than MinStreamFrameSize bytes are left in the packet
+	// First, retransmit lost STREAM frames, before sending any new data.
+	numRetransmissionStreams := f.retransmissionQueue.Len()
+	for i := 0; i < numRetransmissionStreams; i++ {
+		if protocol.MinStreamFrameSize+length > maxLen {
+			break
+		}
+		id := f.retransmissionQueue.PopFront()
+		str, err := f.streamGetter.GetOrOpenSendStream(id)
+		if str == nil || err != nil {
+			delete(f.streamsWithRetransmissions, id)
+			continue
+		}
+		remainingLen := maxLen - length
+		remainingLen += quicvarint.Len(uint64(remainingLen))
+		frame, ok, hasMore := str.popRetransmission(remainingLen, v)
+		if hasMore {
+			f.retransmissionQueue.PushBack(id)
+		} else {
+			delete(f.streamsWithRetransmissions, id)
+		}
+		if !ok {
+			continue
+		}
+		frames = append(frames, frame)
+		length += frame.Frame.Length(v)
+	}
+	// Then pop STREAM frames, until less than MinStreamFrameSize bytes are left in the packet
 	numActiveStreams := f.streamQueue.Len()
 	for i := 0; i < numActiveStreams; i++ {
 		if protocol.MinStreamFrameSize+length > maxLen {
@@ -172,6 +220,10 @@ func (f *framerI) Handle0RTTRejection() error {
 	for id := range f.activeStreams {
 		delete(f.activeStreams, id)
 	}
+	f.retransmissionQueue.Clear()
+	for id := range f.streamsWithRetransmissions {
+		delete(f.streamsWithRetransmissions, id)
+	}
 	var j int
 	for i, frame := range f.controlFrames {
 		switch frame.(type) {
@@ -327,6 +327,52 @@ var _ = Describe("Framer", func() {
 			Expect(frames[1].Frame).To(Equal(f1))
 		})
 
+		It("sends retransmissions before new data", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).Times(2)
+			f1 := &wire.StreamFrame{Data: []byte("foobar")}
+			f2 := &wire.StreamFrame{Data: []byte("lost")}
+			f3 := &wire.StreamFrame{Data: []byte("foobaz")}
+			gomock.InOrder(
+				stream2.EXPECT().popRetransmission(gomock.Any(), protocol.Version1).Return(ackhandler.StreamFrame{Frame: f2}, true, false),
+				stream1.EXPECT().popStreamFrame(gomock.Any(), protocol.Version1).Return(ackhandler.StreamFrame{Frame: f1}, true, false),
+				stream2.EXPECT().popStreamFrame(gomock.Any(), protocol.Version1).Return(ackhandler.StreamFrame{Frame: f3}, true, false),
+			)
+			framer.AddActiveStream(id1)
+			framer.AddStreamWithRetransmission(id2)
+			frames, length := framer.AppendStreamFrames(nil, 1000, protocol.Version1)
+			Expect(frames).To(HaveLen(3))
+			Expect(frames[0].Frame).To(Equal(f2))
+			Expect(frames[1].Frame).To(Equal(f1))
+			Expect(frames[2].Frame).To(Equal(f3))
+			Expect(length).To(Equal(f1.Length(version) + f2.Length(version) + f3.Length(version)))
+		})
+
+		It("keeps serving retransmissions in the next packet, if they didn't fit", func() {
+			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil).Times(2)
+			streamGetter.EXPECT().GetOrOpenSendStream(id2).Return(stream2, nil).Times(4)
+			f1 := &wire.StreamFrame{Data: []byte("foo")}
+			f2 := &wire.StreamFrame{Data: []byte("bar")}
+			f3 := &wire.StreamFrame{Data: []byte("new")}
+			gomock.InOrder(
+				stream2.EXPECT().popRetransmission(gomock.Any(), protocol.Version1).Return(ackhandler.StreamFrame{Frame: f1}, true, true),
+				stream1.EXPECT().popStreamFrame(gomock.Any(), protocol.Version1).Return(ackhandler.StreamFrame{}, false, true),
+				stream2.EXPECT().popStreamFrame(gomock.Any(), protocol.Version1).Return(ackhandler.StreamFrame{}, false, true),
+				stream2.EXPECT().popRetransmission(gomock.Any(), protocol.Version1).Return(ackhandler.StreamFrame{Frame: f2}, true, false),
+				stream1.EXPECT().popStreamFrame(gomock.Any(), protocol.Version1).Return(ackhandler.StreamFrame{Frame: f3}, true, false),
+				stream2.EXPECT().popStreamFrame(gomock.Any(), protocol.Version1).Return(ackhandler.StreamFrame{}, false, false),
+			)
+			framer.AddActiveStream(id1)
+			framer.AddStreamWithRetransmission(id2)
+			frames, _ := framer.AppendStreamFrames(nil, 1000, protocol.Version1)
+			Expect(frames).To(HaveLen(1))
+			Expect(frames[0].Frame).To(Equal(f1))
+			frames, _ = framer.AppendStreamFrames(nil, 1000, protocol.Version1)
+			Expect(frames).To(HaveLen(2))
+			Expect(frames[0].Frame).To(Equal(f2))
+			Expect(frames[1].Frame).To(Equal(f3))
+		})
+
 		It("only asks a stream for data once, even if it was reported active multiple times", func() {
 			streamGetter.EXPECT().GetOrOpenSendStream(id1).Return(stream1, nil)
 			f := &wire.StreamFrame{Data: []byte("foobar")}
@@ -741,4 +741,59 @@ var _ = Describe("Stream Cancellations", func() {
 		Expect(conn.CloseWithError(0, "")).To(Succeed())
 		Eventually(serverRunning).Should(BeClosed())
 	})
+
+	It("accepts a stream that was reset before it was accepted", func() {
+		const connWindow = 15000
+		server, err := quic.ListenAddr(
+			"localhost:0",
+			getTLSConfig(),
+			getQuicConfig(&quic.Config{
+				InitialConnectionReceiveWindow: connWindow,
+				MaxConnectionReceiveWindow:     connWindow,
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer server.Close()
+
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", server.Addr().(*net.UDPAddr).Port),
+			getTLSClientConfig(),
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+
+		// Reset a stream after sending more than half of the connection's flow control window on it.
+		str, err := conn.OpenStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(make([]byte, 10000))
+		Expect(err).ToNot(HaveOccurred())
+		str.CancelWrite(1234)
+		// Sending the data on this stream is only possible if the bytes sent on the reset stream
+		// were accounted for in connection-level flow control.
+		ustr, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = ustr.Write(PRData[:10000])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ustr.Close()).To(Succeed())
+
+		serverConn, err := server.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		serverUniStr, err := serverConn.AcceptUniStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		data, err := io.ReadAll(serverUniStr)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(data).To(Equal(PRData[:10000]))
+
+		serverStr, err := serverConn.AcceptStream(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(serverStr.StreamID()).To(Equal(str.StreamID()))
+		_, err = serverStr.Read([]byte{0})
+		Expect(err).To(Equal(&quic.StreamError{
+			StreamID:  str.StreamID(),
+			ErrorCode: 1234,
+			Remote:    true,
+		}))
+	})
 })
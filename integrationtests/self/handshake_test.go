@@ -379,6 +379,31 @@ var _ = Describe("Handshake tests", func() {
 		})
 	})
 
+	It("reports the key exchange group and the signature scheme", func() {
+		ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		tlsConf := getTLSClientConfig()
+		tlsConf.CurvePreferences = []tls.CurveID{tls.X25519}
+		conn, err := quic.DialAddr(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			tlsConf,
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer conn.CloseWithError(0, "")
+		serverConn, err := ln.Accept(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+
+		cs := conn.ConnectionState()
+		Expect(cs.KeyExchangeGroup).To(Equal(tls.X25519))
+		Expect(cs.SignatureScheme).ToNot(BeZero())
+		Expect(serverConn.ConnectionState().KeyExchangeGroup).To(Equal(tls.X25519))
+		Expect(serverConn.ConnectionState().SignatureScheme).To(Equal(cs.SignatureScheme))
+	})
+
 	Context("ALPN", func() {
 		It("negotiates an application protocol", func() {
 			ln, err := quic.ListenAddr("localhost:0", getTLSConfig(), serverConfig)
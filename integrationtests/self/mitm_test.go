@@ -421,7 +421,11 @@ var _ = Describe("MITM test", func() {
 			closeFn, err := runTest(delayCb)
 			defer closeFn()
 			Expect(err).To(HaveOccurred())
+			// The client can't decrypt any of the server's packets after accepting the forged packet.
+			// The handshake only makes no progress as long as packets are received, so this is an idle timeout.
 			Expect(err.(net.Error).Timeout()).To(BeTrue())
+			var idleTimeoutErr *quic.IdleTimeoutError
+			Expect(errors.As(err, &idleTimeoutErr)).To(BeTrue())
 			Eventually(done).Should(BeClosed())
 		})
 
@@ -241,6 +241,22 @@ var _ = Describe("0-RTT", func() {
 		return zeroRTTPackets
 	}
 
+	// Packet number spaces start at a random packet number.
+	// The returned tracer records the packet number of the first 0-RTT packet sent by the client.
+	newFirst0RTTPacketTracer := func() (*atomic.Int64, func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer) {
+		var firstPN atomic.Int64
+		firstPN.Store(int64(protocol.InvalidPacketNumber))
+		return &firstPN, func(context.Context, logging.Perspective, quic.ConnectionID) *logging.ConnectionTracer {
+			return &logging.ConnectionTracer{
+				SentLongHeaderPacket: func(hdr *logging.ExtendedHeader, _ logging.ByteCount, _ logging.ECN, _ *logging.AckFrame, _ []logging.Frame) {
+					if hdr.Type == protocol.PacketType0RTT {
+						firstPN.CompareAndSwap(int64(protocol.InvalidPacketNumber), int64(hdr.PacketNumber))
+					}
+				},
+			}
+		}
+	}
+
 	for _, l := range []int{0, 15} {
 		connIDLen := l
 
@@ -264,12 +280,13 @@ var _ = Describe("0-RTT", func() {
 			proxy, num0RTTPackets := runCountingProxy(ln.Addr().(*net.UDPAddr).Port)
 			defer proxy.Close()
 
+			first0RTTPacket, clientTracer := newFirst0RTTPacketTracer()
 			transfer0RTTData(
 				ln,
 				proxy.LocalPort(),
 				connIDLen,
 				clientTLSConf,
-				getQuicConfig(nil),
+				getQuicConfig(&quic.Config{Tracer: clientTracer}),
 				PRData,
 			)
 
@@ -292,7 +309,7 @@ var _ = Describe("0-RTT", func() {
 			Expect(num0RTT).ToNot(BeZero())
 			zeroRTTPackets := get0RTTPackets(counter.getRcvdLongHeaderPackets())
 			Expect(len(zeroRTTPackets)).To(BeNumerically(">", 10))
-			Expect(zeroRTTPackets).To(ContainElement(protocol.PacketNumber(0)))
+			Expect(zeroRTTPackets).To(ContainElement(protocol.PacketNumber(first0RTTPacket.Load())))
 		})
 	}
 
@@ -444,12 +461,13 @@ var _ = Describe("0-RTT", func() {
 		var mutex sync.Mutex
 		var firstConnID, secondConnID *protocol.ConnectionID
 		var firstCounter, secondCounter protocol.ByteCount
+		var numSecondPackets int
 
 		tlsConf := getTLSConfig()
 		clientConf := getTLSClientConfig()
 		dialAndReceiveSessionTicket(tlsConf, nil, clientConf)
 
-		countZeroRTTBytes := func(data []byte) (n protocol.ByteCount) {
+		countZeroRTTBytes := func(data []byte) (n protocol.ByteCount, packets int) {
 			for len(data) > 0 {
 				hdr, _, rest, err := wire.ParsePacket(data)
 				if err != nil {
@@ -458,6 +476,7 @@ var _ = Describe("0-RTT", func() {
 				data = rest
 				if hdr.Type == protocol.PacketType0RTT {
 					n += hdr.Length - 16 /* AEAD tag */
+					packets++
 				}
 			}
 			return
@@ -485,7 +504,7 @@ var _ = Describe("0-RTT", func() {
 				mutex.Lock()
 				defer mutex.Unlock()
 
-				if zeroRTTBytes := countZeroRTTBytes(data); zeroRTTBytes > 0 {
+				if zeroRTTBytes, packets := countZeroRTTBytes(data); zeroRTTBytes > 0 {
 					if firstConnID == nil {
 						firstConnID = &connID
 						firstCounter += zeroRTTBytes
@@ -495,8 +514,10 @@ var _ = Describe("0-RTT", func() {
 					} else if secondConnID == nil {
 						secondConnID = &connID
 						secondCounter += zeroRTTBytes
+						numSecondPackets += packets
 					} else if secondConnID != nil && *secondConnID == connID {
 						secondCounter += zeroRTTBytes
+						numSecondPackets += packets
 					} else {
 						Fail("received 3 connection IDs on 0-RTT packets")
 					}
@@ -507,15 +528,19 @@ var _ = Describe("0-RTT", func() {
 		Expect(err).ToNot(HaveOccurred())
 		defer proxy.Close()
 
-		transfer0RTTData(ln, proxy.LocalPort(), protocol.DefaultConnectionIDLength, clientConf, nil, GeneratePRData(5000)) // ~5 packets
+		first0RTTPacket, clientTracer := newFirst0RTTPacketTracer()
+		transfer0RTTData(ln, proxy.LocalPort(), protocol.DefaultConnectionIDLength, clientConf, getQuicConfig(&quic.Config{Tracer: clientTracer}), GeneratePRData(5000)) // ~5 packets
 
 		mutex.Lock()
 		defer mutex.Unlock()
 		Expect(firstCounter).To(BeNumerically("~", 5000+100 /* framing overhead */, 100)) // the FIN bit might be sent extra
-		Expect(secondCounter).To(BeNumerically("~", firstCounter, 20))
+		// When being retransmitted, a STREAM frame might be split, which adds the overhead of one STREAM frame header
+		// per packet: 1 byte frame type, 1 byte stream ID, 2 bytes offset and 2 bytes data length.
+		const maxSplitOverhead = 6
+		Expect(secondCounter).To(BeNumerically("~", firstCounter, 20+protocol.ByteCount(numSecondPackets)*maxSplitOverhead))
 		zeroRTTPackets := get0RTTPackets(counter.getRcvdLongHeaderPackets())
 		Expect(len(zeroRTTPackets)).To(BeNumerically(">=", 5))
-		Expect(zeroRTTPackets[0]).To(BeNumerically(">=", protocol.PacketNumber(5)))
+		Expect(zeroRTTPackets[0]).To(BeNumerically(">=", protocol.PacketNumber(first0RTTPacket.Load())+5))
 	})
 
 	It("doesn't use 0-RTT when Dial is used for the resumed connection", func() {
@@ -628,6 +653,70 @@ var _ = Describe("0-RTT", func() {
 		Expect(get0RTTPackets(counter.getRcvdLongHeaderPackets())).To(BeEmpty())
 	})
 
+	It("resends all data in 1-RTT, respecting the server's new flow control limits, when 0-RTT is rejected", func() {
+		const window = 1 << 20
+		tlsConf := getTLSConfig()
+		clientConf := getTLSClientConfig()
+		dialAndReceiveSessionTicket(tlsConf, getQuicConfig(&quic.Config{
+			InitialStreamReceiveWindow:     window,
+			InitialConnectionReceiveWindow: window,
+		}), clientConf)
+
+		// The server reduced its connection flow control limit, so it rejects 0-RTT.
+		ln, err := quic.ListenAddrEarly(
+			"localhost:0",
+			tlsConf,
+			getQuicConfig(&quic.Config{
+				InitialStreamReceiveWindow:     window,
+				InitialConnectionReceiveWindow: window / 10,
+				Allow0RTT:                      true,
+			}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		defer ln.Close()
+
+		received := make(chan []byte, 1)
+		go func() {
+			defer GinkgoRecover()
+			conn, err := ln.Accept(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			str, err := conn.AcceptUniStream(context.Background())
+			Expect(err).ToNot(HaveOccurred())
+			// Don't read right away, so the client has to respect the initial connection flow control window.
+			time.Sleep(scaleDuration(100 * time.Millisecond))
+			data, err := io.ReadAll(str)
+			Expect(err).ToNot(HaveOccurred())
+			received <- data
+		}()
+
+		conn, err := quic.DialAddrEarly(
+			context.Background(),
+			fmt.Sprintf("localhost:%d", ln.Addr().(*net.UDPAddr).Port),
+			clientConf,
+			getQuicConfig(nil),
+		)
+		Expect(err).ToNot(HaveOccurred())
+		// The client remembers the old limit, and sends up to window bytes in 0-RTT.
+		str, err := conn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(PRData)
+		if err != nil {
+			Expect(err).To(MatchError(quic.Err0RTTRejected))
+		}
+
+		newConn := conn.NextConnection()
+		Expect(newConn.ConnectionState().Used0RTT).To(BeFalse())
+		str, err = newConn.OpenUniStream()
+		Expect(err).ToNot(HaveOccurred())
+		_, err = str.Write(PRData)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.Close()).To(Succeed())
+		var data []byte
+		Eventually(received, 5*time.Second).Should(Receive(&data))
+		Expect(data).To(Equal(PRData))
+		Expect(conn.CloseWithError(0, "")).To(Succeed())
+	})
+
 	It("rejects 0-RTT when the ALPN changed", func() {
 		tlsConf := getTLSConfig()
 		clientConf := getTLSClientConfig()
@@ -918,12 +1007,13 @@ var _ = Describe("0-RTT", func() {
 		Expect(err).ToNot(HaveOccurred())
 		defer proxy.Close()
 
-		transfer0RTTData(ln, proxy.LocalPort(), protocol.DefaultConnectionIDLength, clientConf, nil, PRData)
+		first0RTTPacket, clientTracer := newFirst0RTTPacketTracer()
+		transfer0RTTData(ln, proxy.LocalPort(), protocol.DefaultConnectionIDLength, clientConf, getQuicConfig(&quic.Config{Tracer: clientTracer}), PRData)
 
 		Expect(counter.getRcvdLongHeaderPackets()[0].hdr.Type).To(Equal(protocol.PacketTypeInitial))
 		zeroRTTPackets := get0RTTPackets(counter.getRcvdLongHeaderPackets())
 		Expect(len(zeroRTTPackets)).To(BeNumerically(">", 10))
-		Expect(zeroRTTPackets[0]).To(Equal(protocol.PacketNumber(0)))
+		Expect(zeroRTTPackets[0]).To(Equal(protocol.PacketNumber(first0RTTPacket.Load())))
 	})
 
 	It("allows the application to attach data to the session ticket, for the server", func() {
@@ -26,6 +26,14 @@ const (
 	Version2 = protocol.Version2
 )
 
+// SupportedVersions returns the QUIC versions supported by this implementation.
+// Unless Config.Versions is set, these versions are offered when dialing and accepted by servers,
+// in order of preference.
+func SupportedVersions() []VersionNumber {
+	// copy the slice, so that the caller can't modify the versions we offer
+	return append([]VersionNumber{}, protocol.SupportedVersions...)
+}
+
 // A ClientToken is a token received by the client.
 // It can be used to skip address validation on future connection attempts.
 type ClientToken struct {
@@ -67,6 +75,9 @@ var QUICVersionContextKey = handshake.QUICVersionContextKey
 type Stream interface {
 	ReceiveStream
 	SendStream
+	// IsLocal returns true if the stream was opened by us,
+	// and false if it was opened by the peer.
+	IsLocal() bool
 	// SetDeadline sets the read and write deadlines associated
 	// with the connection. It is equivalent to calling both
 	// SetReadDeadline and SetWriteDeadline.
@@ -125,6 +136,11 @@ type SendStream interface {
 	// The cancellation cause is set to the error that caused the stream to
 	// close, or `context.Canceled` in case the stream is closed without error.
 	Context() context.Context
+	// WaitUntilAcked blocks until all data written to the stream, including the FIN, was acknowledged by the peer.
+	// The FIN is only sent after Close is called.
+	// It returns an error if the context is canceled, if the stream is canceled,
+	// or if the connection is closed before all data was acknowledged.
+	WaitUntilAcked(context.Context) error
 	// SetWriteDeadline sets the deadline for future Write calls
 	// and any currently-blocked Write call.
 	// Even if write times out, it may return n > 0, indicating that
@@ -139,6 +155,8 @@ type SendStream interface {
 // * TransportError: for errors triggered by the QUIC transport (in many cases a misbehaving peer)
 // * IdleTimeoutError: when the peer goes away unexpectedly (this is a net.Error timeout error)
 // * HandshakeTimeoutError: when the cryptographic handshake takes too long (this is a net.Error timeout error)
+// * HandshakeNoProgressError: when the peer keeps sending packets, but the handshake makes no progress (this is a net.Error timeout error)
+// * MaxConnectionDurationError: when the connection reached the maximum lifetime configured by Config.MaxConnectionDuration
 // * StatelessResetError: when we receive a stateless reset (this is a net.Error temporary error)
 // * VersionNegotiationError: returned by the client, when there's no version overlap between the peers
 type Connection interface {
@@ -186,6 +204,19 @@ type Connection interface {
 	// ConnectionState returns basic details about the QUIC connection.
 	// Warning: This API should not be considered stable and might change soon.
 	ConnectionState() ConnectionState
+	// MaxPacketSize returns the maximum size of QUIC packets currently sent on this connection.
+	// It starts at a conservative value, and increases as Path MTU Discovery discovers larger packet sizes.
+	MaxPacketSize() int
+	// LastActivity returns the time when the last packet was sent or received on this connection.
+	LastActivity() time.Time
+	// ProbePath checks if the peer is reachable from localAddr, without migrating the connection.
+	// It sends a PATH_CHALLENGE frame from a new UDP socket bound to localAddr, and returns true
+	// if the peer's PATH_RESPONSE is received before the path validation timeout.
+	// The connection continues to use the current path.
+	// Only clients can probe paths, and only if the server didn't disable active connection migration.
+	// The probe is only sent once the handshake is confirmed. If ctx is canceled before the
+	// path validation completes, ProbePath returns the context's error.
+	ProbePath(ctx context.Context, localAddr net.Addr) (bool, error)
 
 	// SendDatagram sends a message as a datagram, as specified in RFC 9221.
 	SendDatagram([]byte) error
@@ -255,6 +286,8 @@ type Config struct {
 	// HandshakeIdleTimeout is the idle timeout before completion of the handshake.
 	// If we don't receive any packet from the peer within this time, the connection attempt is aborted.
 	// Additionally, if the handshake doesn't complete in twice this time, the connection attempt is also aborted.
+	// If packets are received, but the handshake doesn't make any progress for 1.5 times this time,
+	// the connection attempt is aborted with a HandshakeNoProgressError.
 	// If this value is zero, the timeout is set to 5 seconds.
 	HandshakeIdleTimeout time.Duration
 	// MaxIdleTimeout is the maximum duration that may pass without any incoming network activity.
@@ -300,6 +333,11 @@ type Config struct {
 	// To avoid deadlocks, it is not valid to call other functions on the connection or on streams
 	// in this callback.
 	AllowConnectionWindowIncrease func(conn Connection, delta uint64) bool
+	// MaxSendBufferSize is the maximum number of bytes that are sent on all streams of a connection,
+	// but haven't been acknowledged by the peer yet.
+	// Once this limit is reached, calls to Write block until the peer acknowledges data.
+	// If not set, the number of outstanding bytes is only limited by flow control.
+	MaxSendBufferSize uint64
 	// MaxIncomingStreams is the maximum number of concurrent bidirectional streams that a peer is allowed to open.
 	// If not set, it will default to 100.
 	// If set to a negative value, it doesn't allow any bidirectional streams.
@@ -314,17 +352,53 @@ type Config struct {
 	// If set to 0, then no keep alive is sent. Otherwise, the keep alive is sent on that period (or at most
 	// every half of MaxIdleTimeout, whichever is smaller).
 	KeepAlivePeriod time.Duration
+	// MaxConnectionDuration is the maximum lifetime of a connection, measured from the time it was created.
+	// Once it has elapsed, the connection is closed with a MaxConnectionDurationError,
+	// regardless of the activity on the connection. The peer receives an application error (error code 0).
+	// If zero, the lifetime of a connection is not limited.
+	MaxConnectionDuration time.Duration
 	// DisablePathMTUDiscovery disables Path MTU Discovery (RFC 8899).
 	// This allows the sending of QUIC packets that fully utilize the available MTU of the path.
 	// Path MTU discovery is only available on systems that allow setting of the Don't Fragment (DF) bit.
 	// If unavailable or disabled, packets will be at most 1252 (IPv4) / 1232 (IPv6) bytes in size.
 	DisablePathMTUDiscovery bool
+	// DisableSpinBit disables the latency spin bit (RFC 9000, section 17.4).
+	// If disabled, the spin bit in short header packets is set to a random value,
+	// and the value of the spin bit on packets received from the peer is ignored.
+	// Even if enabled, the spin bit is disabled on one in every 16 connections, as required by RFC 9000.
+	DisableSpinBit bool
+	// MaxGSOBatchSize is the maximum number of packets that are passed to the kernel in a single
+	// call when using Generic Segmentation Offload (GSO).
+	// If not set, it will default to 64, the maximum number of segments supported by the Linux kernel.
+	// Values larger than 64 will be clipped to that value.
+	// Independent of this value, a batch is never larger than 20 kB.
+	MaxGSOBatchSize int
+	// AckElicitingThreshold is the number of ack-eliciting packets that are received
+	// before an acknowledgement is sent immediately. Otherwise, the acknowledgement is delayed
+	// by up to the maximum ACK delay.
+	// Smaller values reduce the latency of loss detection at the peer, larger values reduce the number of ACKs sent.
+	// If not set, it will default to 2.
+	// This only applies to 1-RTT packets, Initial and Handshake packets are always acknowledged every 2 packets.
+	AckElicitingThreshold int
+	// MaxAckRanges is the maximum number of ACK ranges sent in a single ACK frame.
+	// If more ranges need to be acknowledged, the ranges for the oldest packets are omitted.
+	// Smaller values keep ACK frames small, at the cost of acknowledging fewer packets in the presence of packet loss or reordering.
+	// If not set, it will default to 32.
+	// Values larger than 32 will be clipped to that value.
+	// This only applies to 1-RTT packets.
+	MaxAckRanges int
 	// Allow0RTT allows the application to decide if a 0-RTT connection attempt should be accepted.
 	// Only valid for the server.
 	Allow0RTT bool
 	// Enable QUIC datagram support (RFC 9221).
 	EnableDatagrams bool
 	Tracer          func(context.Context, logging.Perspective, ConnectionID) *logging.ConnectionTracer
+	// OnConnectionError is called once when a connection is closed due to an error,
+	// for example when the connection times out, or when it is closed by the peer.
+	// It is not called when the application closes the connection using CloseWithError.
+	// It is called when the connection is closed because Config.MaxConnectionDuration elapsed.
+	// It is called from the connection's run loop, and must not block.
+	OnConnectionError func(err error)
 }
 
 type ClientHelloInfo struct {
@@ -342,6 +416,11 @@ type ConnectionState struct {
 	SupportsDatagrams bool
 	// Used0RTT says if 0-RTT resumption was used.
 	Used0RTT bool
+	// KeyExchangeGroup is the key exchange group (e.g. X25519) negotiated in the TLS handshake.
+	KeyExchangeGroup tls.CurveID
+	// SignatureScheme is the signature algorithm the server used to sign the TLS handshake.
+	// It is 0 if the server didn't authenticate using a certificate, e.g. when a session was resumed.
+	SignatureScheme tls.SignatureScheme
 	// Version is the QUIC version of the QUIC connection.
 	Version VersionNumber
 	// GSO says if generic segmentation offload is used
@@ -9,16 +9,30 @@ import (
 // NewAckHandler creates a new SentPacketHandler and a new ReceivedPacketHandler.
 // clientAddressValidated indicates whether the address was validated beforehand by an address validation token.
 // clientAddressValidated has no effect for a client.
+// The Initial packet number space starts at initialPacketNumber.
+// If initialPacketNumber is protocol.InvalidPacketNumber, it starts at a random packet number.
+// The Handshake and the application data packet number spaces always start at a random packet number.
+// These random packet numbers are drawn from randSource. If randSource is nil, crypto/rand is used.
+// ackElicitingThreshold is the number of ack-eliciting 1-RTT packets received before an ACK is sent.
 func NewAckHandler(
 	initialPacketNumber protocol.PacketNumber,
+	randSource RandSource,
 	initialMaxDatagramSize protocol.ByteCount,
 	rttStats *utils.RTTStats,
 	clientAddressValidated bool,
 	enableECN bool,
+	ackElicitingThreshold int,
 	pers protocol.Perspective,
 	tracer *logging.ConnectionTracer,
 	logger utils.Logger,
 ) (SentPacketHandler, ReceivedPacketHandler) {
+	if randSource == nil {
+		randSource = &utils.Rand{}
+	}
+	if initialPacketNumber == protocol.InvalidPacketNumber {
+		initialPacketNumber = randomInitialPacketNumber(randSource)
+	}
 	sph := newSentPacketHandler(initialPacketNumber, initialMaxDatagramSize, rttStats, clientAddressValidated, enableECN, pers, tracer, logger)
-	return sph, newReceivedPacketHandler(sph, rttStats, logger)
+	sph.randomizeInitialPacketNumbers(randSource)
+	return sph, newReceivedPacketHandler(sph, rttStats, ackElicitingThreshold, logger)
 }
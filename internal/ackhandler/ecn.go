@@ -194,6 +194,8 @@ func (e *ecnTracker) HandleNewlyAcked(packets []*packet, ect0, ect1, ecnce int64
 	// We're only processing ACKs that increase the Largest Acked.
 	// Therefore, the ECN counters should only ever increase.
 	// Any decrease means that the peer's counting logic is broken.
+	// This happens if the peer stops reporting ECN counts: the sentPacketHandler only rejects
+	// decreasing ECN counts if the ACK frame contains ECN counts.
 	if newECT0 < 0 || newECT1 < 0 || newECNCE < 0 {
 		e.logger.Debugf("Disabling ECN. ECN counts decreased unexpectedly.")
 		if e.tracer != nil && e.tracer.ECNStateUpdated != nil {
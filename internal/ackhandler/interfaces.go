@@ -3,14 +3,17 @@ package ackhandler
 import (
 	"time"
 
+	"github.com/quic-go/quic-go/internal/congestion"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/wire"
 )
 
 // SentPacketHandler handles ACKs received for outgoing packets
 type SentPacketHandler interface {
-	// SentPacket may modify the packet
-	SentPacket(t time.Time, pn, largestAcked protocol.PacketNumber, streamFrames []StreamFrame, frames []Frame, encLevel protocol.EncryptionLevel, ecn protocol.ECN, size protocol.ByteCount, isPathMTUProbePacket bool)
+	// SentPacket may modify the packet.
+	// Path probe packets are sent on a different path. They only consume a packet number,
+	// but are not tracked for congestion control and loss detection.
+	SentPacket(t time.Time, pn, largestAcked protocol.PacketNumber, streamFrames []StreamFrame, frames []Frame, encLevel protocol.EncryptionLevel, ecn protocol.ECN, size protocol.ByteCount, isPathMTUProbePacket, isPathProbePacket bool)
 	// ReceivedAck processes an ACK frame.
 	// It does not store a copy of the frame.
 	ReceivedAck(f *wire.AckFrame, encLevel protocol.EncryptionLevel, rcvTime time.Time) (bool /* 1-RTT packet acked */, error)
@@ -25,6 +28,10 @@ type SentPacketHandler interface {
 	// It is used for pacing packets.
 	TimeUntilSend() time.Time
 	SetMaxDatagramSize(count protocol.ByteCount)
+	// SetCongestionController replaces the congestion controller.
+	// Bytes in flight are tracked by the SentPacketHandler, and are therefore preserved.
+	// The new congestion controller inherits the congestion window, the bytes in flight and the max datagram size.
+	SetCongestionController(congestion.SendAlgorithmWithDebugInfos)
 
 	// only to be called once the handshake is complete
 	QueueProbePacket(protocol.EncryptionLevel) bool /* was a packet queued */
@@ -34,7 +41,7 @@ type SentPacketHandler interface {
 	PopPacketNumber(protocol.EncryptionLevel) protocol.PacketNumber
 
 	GetLossDetectionTimeout() time.Time
-	OnLossDetectionTimeout() error
+	OnLossDetectionTimeout(now time.Time) error
 }
 
 type sentPacketTracker interface {
@@ -5,6 +5,23 @@ import (
 	"github.com/quic-go/quic-go/internal/utils"
 )
 
+// maxRandomInitialPacketNumber is the (exclusive) upper bound for randomly chosen initial packet numbers.
+// It is small enough that the first packet number can be encoded using 2 bytes,
+// and decoded by a peer that hasn't received any packet in this packet number space yet.
+const maxRandomInitialPacketNumber = 1 << 14
+
+// A RandSource is used to choose the initial packet numbers.
+// utils.Rand and math/rand.Rand both implement it.
+type RandSource interface {
+	Int31n(n int32) int32
+}
+
+// randomInitialPacketNumber returns a random packet number in the range [0, 2^14).
+// Starting a packet number space at a random packet number makes it harder to link connections.
+func randomInitialPacketNumber(r RandSource) protocol.PacketNumber {
+	return protocol.PacketNumber(r.Int31n(maxRandomInitialPacketNumber))
+}
+
 type packetNumberGenerator interface {
 	Peek() protocol.PacketNumber
 	// Pop pops the packet number.
@@ -3,13 +3,102 @@ package ackhandler
 import (
 	"fmt"
 	"math"
+	"math/rand"
 
+	"github.com/quic-go/quic-go/internal/handshake"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
 
+var _ = Describe("Random Initial Packet Numbers", func() {
+	It("chooses bounded random packet numbers", func() {
+		var largest protocol.PacketNumber
+		for i := 0; i < 1000; i++ {
+			pn := randomInitialPacketNumber(&utils.Rand{})
+			Expect(pn).To(And(BeNumerically(">=", 0), BeNumerically("<", maxRandomInitialPacketNumber)))
+			largest = max(largest, pn)
+		}
+		Expect(largest).To(BeNumerically(">", maxRandomInitialPacketNumber/2))
+	})
+
+	It("is deterministic when using a seeded source", func() {
+		const seed = 1337
+		expected := protocol.PacketNumber(rand.New(rand.NewSource(seed)).Int31n(maxRandomInitialPacketNumber))
+
+		sph := newSentPacketHandler(0, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), false, false, protocol.PerspectiveClient, nil, utils.DefaultLogger)
+		sph.randomizeInitialPacketNumbers(rand.New(rand.NewSource(seed)))
+		pn, pnLen := sph.PeekPacketNumber(protocol.EncryptionHandshake)
+		Expect(pn).To(Equal(expected))
+		Expect(sph.PopPacketNumber(protocol.EncryptionHandshake)).To(Equal(expected))
+
+		// The peer hasn't received any packets in this packet number space yet.
+		// Make sure it is able to decode the truncated packet number.
+		truncated := pn & (1<<(8*pnLen) - 1)
+		Expect(protocol.DecodePacketNumber(pnLen, 0, truncated)).To(Equal(pn))
+	})
+
+	It("randomizes the initial packet numbers of all packet number spaces", func() {
+		sph, _ := NewAckHandler(protocol.InvalidPacketNumber, nil, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), false, false, 2, protocol.PerspectiveClient, nil, utils.DefaultLogger)
+		for _, encLevel := range []protocol.EncryptionLevel{protocol.EncryptionInitial, protocol.EncryptionHandshake, protocol.Encryption1RTT} {
+			pn, _ := sph.PeekPacketNumber(encLevel)
+			Expect(pn).To(And(BeNumerically(">=", 0), BeNumerically("<", maxRandomInitialPacketNumber)))
+		}
+	})
+
+	It("uses the initial packet number for the Initial packet number space", func() {
+		sph, _ := NewAckHandler(1337, nil, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), false, false, 2, protocol.PerspectiveClient, nil, utils.DefaultLogger)
+		Expect(sph.PopPacketNumber(protocol.EncryptionInitial)).To(Equal(protocol.PacketNumber(1337)))
+	})
+
+	It("uses the random source to choose the initial packet numbers", func() {
+		const seed = 42
+		r := rand.New(rand.NewSource(seed))
+		var expected []protocol.PacketNumber
+		for i := 0; i < 3; i++ {
+			expected = append(expected, protocol.PacketNumber(r.Int31n(maxRandomInitialPacketNumber)))
+		}
+
+		sph, _ := NewAckHandler(protocol.InvalidPacketNumber, rand.New(rand.NewSource(seed)), protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), false, false, 2, protocol.PerspectiveClient, nil, utils.DefaultLogger)
+		for i, encLevel := range []protocol.EncryptionLevel{protocol.EncryptionInitial, protocol.EncryptionHandshake, protocol.Encryption1RTT} {
+			pn, _ := sph.PeekPacketNumber(encLevel)
+			Expect(pn).To(Equal(expected[i]))
+		}
+	})
+
+	It("sends packet numbers that the peer decodes correctly", func() {
+		connID := protocol.ParseConnectionID([]byte{0xde, 0xad, 0xbe, 0xef})
+		for seed := int64(0); seed < 100; seed++ {
+			sph, _ := NewAckHandler(protocol.InvalidPacketNumber, rand.New(rand.NewSource(seed)), protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), false, false, 2, protocol.PerspectiveClient, nil, utils.DefaultLogger)
+			for _, encLevel := range []protocol.EncryptionLevel{protocol.EncryptionInitial, protocol.EncryptionHandshake, protocol.Encryption1RTT} {
+				// Every packet number space uses a fresh opener on the receiver side.
+				sealer, _ := handshake.NewInitialAEAD(connID, protocol.PerspectiveClient, protocol.Version1)
+				_, opener := handshake.NewInitialAEAD(connID, protocol.PerspectiveServer, protocol.Version1)
+				for i := 0; i < 10; i++ {
+					pn, pnLen := sph.PeekPacketNumber(encLevel)
+					Expect(sph.PopPacketNumber(encLevel)).To(Equal(pn))
+					sealed := sealer.Seal(nil, []byte("foobar"), pn, nil)
+
+					decoded := opener.DecodePacketNumber(pn&(1<<(8*pnLen)-1), pnLen)
+					Expect(decoded).To(Equal(pn), fmt.Sprintf("seed %d, %s", seed, encLevel))
+					data, err := opener.Open(nil, sealed, decoded, nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal([]byte("foobar")))
+				}
+			}
+		}
+	})
+
+	It("encodes and decodes the largest random initial packet number", func() {
+		const pn = maxRandomInitialPacketNumber - 1
+		pnLen := protocol.GetPacketNumberLengthForHeader(pn, protocol.InvalidPacketNumber)
+		Expect(pnLen).To(Equal(protocol.PacketNumberLen2))
+		Expect(protocol.DecodePacketNumber(pnLen, 0, pn&0xffff)).To(Equal(protocol.PacketNumber(pn)))
+	})
+})
+
 var _ = Describe("Sequential Packet Number Generator", func() {
 	It("generates sequential packet numbers", func() {
 		const initialPN protocol.PacketNumber = 123
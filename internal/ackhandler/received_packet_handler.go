@@ -24,13 +24,14 @@ var _ ReceivedPacketHandler = &receivedPacketHandler{}
 func newReceivedPacketHandler(
 	sentPackets sentPacketTracker,
 	rttStats *utils.RTTStats,
+	ackElicitingThreshold int,
 	logger utils.Logger,
 ) ReceivedPacketHandler {
 	return &receivedPacketHandler{
 		sentPackets:      sentPackets,
-		initialPackets:   newReceivedPacketTracker(rttStats, logger),
-		handshakePackets: newReceivedPacketTracker(rttStats, logger),
-		appDataPackets:   newReceivedPacketTracker(rttStats, logger),
+		initialPackets:   newReceivedPacketTracker(rttStats, protocol.DefaultAckElicitingThreshold, logger),
+		handshakePackets: newReceivedPacketTracker(rttStats, protocol.DefaultAckElicitingThreshold, logger),
+		appDataPackets:   newReceivedPacketTracker(rttStats, ackElicitingThreshold, logger),
 		lowest1RTTPacket: protocol.InvalidPacketNumber,
 	}
 }
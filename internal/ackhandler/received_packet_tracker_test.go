@@ -19,7 +19,7 @@ var _ = Describe("Received Packet Tracker", func() {
 
 	BeforeEach(func() {
 		rttStats = &utils.RTTStats{}
-		tracker = newReceivedPacketTracker(rttStats, utils.DefaultLogger)
+		tracker = newReceivedPacketTracker(rttStats, protocol.DefaultAckElicitingThreshold, utils.DefaultLogger)
 	})
 
 	Context("accepting packets", func() {
@@ -105,6 +105,37 @@ var _ = Describe("Received Packet Tracker", func() {
 				}
 			})
 
+			It("queues an ACK for every ack-eliciting packet, if the threshold is 1", func() {
+				tracker.packetsBeforeAck = 1
+				receiveAndAck10Packets()
+				for p := protocol.PacketNumber(11); p <= 20; p++ {
+					Expect(tracker.ReceivedPacket(p, protocol.ECNNon, time.Time{}, true)).To(Succeed())
+					Expect(tracker.ackQueued).To(BeTrue())
+					Expect(tracker.GetAlarmTimeout()).To(BeZero())
+					// dequeue the ACK frame
+					Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
+				}
+			})
+
+			It("delays the ACK if the threshold is larger", func() {
+				tracker.packetsBeforeAck = 5
+				receiveAndAck10Packets()
+				p := protocol.PacketNumber(11)
+				for i := 0; i < 3; i++ {
+					for j := 0; j < 4; j++ {
+						Expect(tracker.ReceivedPacket(p, protocol.ECNNon, time.Now(), true)).To(Succeed())
+						Expect(tracker.ackQueued).To(BeFalse())
+						Expect(tracker.GetAlarmTimeout()).ToNot(BeZero())
+						p++
+					}
+					Expect(tracker.ReceivedPacket(p, protocol.ECNNon, time.Now(), true)).To(Succeed())
+					Expect(tracker.ackQueued).To(BeTrue())
+					p++
+					// dequeue the ACK frame
+					Expect(tracker.GetAckFrame(true)).ToNot(BeNil())
+				}
+			})
+
 			It("resets the counter when a non-queued ACK frame is generated", func() {
 				receiveAndAck10Packets()
 				rcvTime := time.Now()
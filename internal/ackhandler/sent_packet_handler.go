@@ -25,6 +25,9 @@ const (
 	minRTTAfterRetry = 5 * time.Millisecond
 	// The PTO duration uses exponential backoff, but is truncated to a maximum value, as allowed by RFC 8961, section 4.4.
 	maxPTODuration = 60 * time.Second
+	// The maximum number of packets declared lost that we keep track of (per packet number space),
+	// in order to detect spurious losses.
+	maxTrackedLostPackets = 64
 )
 
 type packetNumberSpace struct {
@@ -36,6 +39,16 @@ type packetNumberSpace struct {
 
 	largestAcked protocol.PacketNumber
 	largestSent  protocol.PacketNumber
+	// Packet number spaces start at a random packet number.
+	// Any packet number below the first packet sent was never sent.
+	firstSent protocol.PacketNumber
+
+	// the ECN counts reported on the ACK frame that acknowledged the largest acked packet
+	ect0, ect1, ecnce uint64
+
+	// packet numbers of packets that were declared lost, in the order they were declared lost
+	// If one of them is acknowledged later, the loss was spurious.
+	lostPackets []protocol.PacketNumber
 }
 
 func newPacketNumberSpace(initialPN protocol.PacketNumber, skipPNs bool) *packetNumberSpace {
@@ -50,7 +63,15 @@ func newPacketNumberSpace(initialPN protocol.PacketNumber, skipPNs bool) *packet
 		pns:          pns,
 		largestSent:  protocol.InvalidPacketNumber,
 		largestAcked: protocol.InvalidPacketNumber,
+		firstSent:    protocol.InvalidPacketNumber,
+	}
+}
+
+func (s *packetNumberSpace) trackLostPacket(p *packet) {
+	if len(s.lostPackets) >= maxTrackedLostPackets {
+		s.lostPackets = s.lostPackets[1:]
 	}
+	s.lostPackets = append(s.lostPackets, p.PacketNumber)
 }
 
 type sentPacketHandler struct {
@@ -79,8 +100,9 @@ type sentPacketHandler struct {
 
 	bytesInFlight protocol.ByteCount
 
-	congestion congestion.SendAlgorithmWithDebugInfos
-	rttStats   *utils.RTTStats
+	maxDatagramSize protocol.ByteCount
+	congestion      congestion.SendAlgorithmWithDebugInfos
+	rttStats        *utils.RTTStats
 
 	// The number of times a PTO has been sent without receiving an ack.
 	ptoCount uint32
@@ -133,6 +155,7 @@ func newSentPacketHandler(
 		handshakePackets:               newPacketNumberSpace(0, false),
 		appDataPackets:                 newPacketNumberSpace(0, true),
 		rttStats:                       rttStats,
+		maxDatagramSize:                initialMaxDatagramSize,
 		congestion:                     congestion,
 		perspective:                    pers,
 		tracer:                         tracer,
@@ -145,6 +168,13 @@ func newSentPacketHandler(
 	return h
 }
 
+// randomizeInitialPacketNumbers makes the Handshake and the application data packet number spaces start at a random packet number.
+// It must be called before any packet is sent.
+func (h *sentPacketHandler) randomizeInitialPacketNumbers(r RandSource) {
+	h.handshakePackets = newPacketNumberSpace(randomInitialPacketNumber(r), false)
+	h.appDataPackets = newPacketNumberSpace(randomInitialPacketNumber(r), true)
+}
+
 func (h *sentPacketHandler) removeFromBytesInFlight(p *packet) {
 	if p.includedInBytesInFlight {
 		if p.Length > h.bytesInFlight {
@@ -240,6 +270,7 @@ func (h *sentPacketHandler) SentPacket(
 	ecn protocol.ECN,
 	size protocol.ByteCount,
 	isPathMTUProbePacket bool,
+	isPathProbePacket bool,
 ) {
 	h.bytesSent += size
 
@@ -250,7 +281,19 @@ func (h *sentPacketHandler) SentPacket(
 		}
 	}
 
+	if pnSpace.firstSent == protocol.InvalidPacketNumber {
+		pnSpace.firstSent = pn
+	}
 	pnSpace.largestSent = pn
+
+	if isPathProbePacket {
+		// Path probe packets are sent on a different path, and are never retransmitted.
+		// They don't count towards the bytes in flight of the current path, and aren't passed to the congestion controller.
+		// If the peer acknowledges them, the acknowledgement is ignored, just as for non-ack-eliciting packets.
+		pnSpace.history.SentNonAckElicitingPacket(pn)
+		return
+	}
+
 	isAckEliciting := len(streamFrames) > 0 || len(frames) > 0
 
 	if isAckEliciting {
@@ -309,13 +352,28 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	pnSpace := h.getPacketNumberSpace(encLevel)
 
 	largestAcked := ack.LargestAcked()
-	if largestAcked > pnSpace.largestSent {
+	if largestAcked > pnSpace.largestSent || ack.LowestAcked() < pnSpace.firstSent {
 		return false, &qerr.TransportError{
 			ErrorCode:    qerr.ProtocolViolation,
 			ErrorMessage: "received ACK for an unsent packet",
 		}
 	}
 
+	// ECN counts are cumulative, and must never decrease.
+	// Reordered ACK frames might carry smaller counts, so only check ACKs that increase the largest acked.
+	// ACK frames without ECN counts are handled by the ECN tracker, which then disables ECN.
+	if largestAcked > pnSpace.largestAcked && (ack.ECT0 > 0 || ack.ECT1 > 0 || ack.ECNCE > 0) {
+		if ack.ECT0 < pnSpace.ect0 || ack.ECT1 < pnSpace.ect1 || ack.ECNCE < pnSpace.ecnce {
+			return false, &qerr.TransportError{
+				ErrorCode:    qerr.ProtocolViolation,
+				ErrorMessage: "decreased ECN counts",
+			}
+		}
+		pnSpace.ect0 = ack.ECT0
+		pnSpace.ect1 = ack.ECT1
+		pnSpace.ecnce = ack.ECNCE
+	}
+
 	// Servers complete address validation when a protected packet is received.
 	if h.perspective == protocol.PerspectiveClient && !h.peerCompletedAddressValidation &&
 		(encLevel == protocol.EncryptionHandshake || encLevel == protocol.Encryption1RTT) {
@@ -327,9 +385,13 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 
 	priorInFlight := h.bytesInFlight
 	ackedPackets, err := h.detectAndRemoveAckedPackets(ack, encLevel)
-	if err != nil || len(ackedPackets) == 0 {
+	if err != nil {
 		return false, err
 	}
+	h.detectSpuriousLosses(ack, encLevel)
+	if len(ackedPackets) == 0 {
+		return false, nil
+	}
 	// update the RTT, if the largest acked is newly acknowledged
 	if len(ackedPackets) > 0 {
 		if p := ackedPackets[len(ackedPackets)-1]; p.PacketNumber == ack.LargestAcked() {
@@ -391,6 +453,29 @@ func (h *sentPacketHandler) ReceivedAck(ack *wire.AckFrame, encLevel protocol.En
 	return acked1RTTPacket, nil
 }
 
+// detectSpuriousLosses checks if the ACK acknowledges any packets that we already declared lost.
+// These packets were already removed from the history, and their frames were queued for retransmission,
+// so we must not process them again. However, the congestion controller might undo the window reduction.
+func (h *sentPacketHandler) detectSpuriousLosses(ack *wire.AckFrame, encLevel protocol.EncryptionLevel) {
+	pnSpace := h.getPacketNumberSpace(encLevel)
+	if len(pnSpace.lostPackets) == 0 {
+		return
+	}
+	var j int
+	for _, pn := range pnSpace.lostPackets {
+		if !ack.AcksPacket(pn) {
+			pnSpace.lostPackets[j] = pn
+			j++
+			continue
+		}
+		if h.logger.Debug() {
+			h.logger.Debugf("\tspurious loss of packet %d (%s)", pn, encLevel)
+		}
+		h.congestion.OnSpuriousCongestionEvent(pn)
+	}
+	pnSpace.lostPackets = pnSpace.lostPackets[:j]
+}
+
 func (h *sentPacketHandler) GetLowestPacketNotConfirmedAcked() protocol.PacketNumber {
 	return h.lowestNotConfirmedAcked
 }
@@ -659,6 +744,7 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 				h.queueFramesForRetransmission(p)
 				if !p.IsPathMTUProbePacket {
 					h.congestion.OnCongestionEvent(p.PacketNumber, p.Length, priorInFlight)
+					pnSpace.trackLostPacket(p)
 				}
 				if encLevel == protocol.Encryption1RTT && h.ecnTracker != nil {
 					h.ecnTracker.LostPacket(p.PacketNumber)
@@ -669,7 +755,7 @@ func (h *sentPacketHandler) detectLostPackets(now time.Time, encLevel protocol.E
 	})
 }
 
-func (h *sentPacketHandler) OnLossDetectionTimeout() error {
+func (h *sentPacketHandler) OnLossDetectionTimeout(now time.Time) error {
 	defer h.setLossDetectionTimer()
 	earliestLossTime, encLevel := h.getLossTimeAndSpace()
 	if !earliestLossTime.IsZero() {
@@ -680,7 +766,7 @@ func (h *sentPacketHandler) OnLossDetectionTimeout() error {
 			h.tracer.LossTimerExpired(logging.TimerTypeACK, encLevel)
 		}
 		// Early retransmit or time loss detection
-		return h.detectLostPackets(time.Now(), encLevel)
+		return h.detectLostPackets(now, encLevel)
 	}
 
 	// PTO
@@ -822,9 +908,18 @@ func (h *sentPacketHandler) TimeUntilSend() time.Time {
 }
 
 func (h *sentPacketHandler) SetMaxDatagramSize(s protocol.ByteCount) {
+	h.maxDatagramSize = s
 	h.congestion.SetMaxDatagramSize(s)
 }
 
+func (h *sentPacketHandler) SetCongestionController(c congestion.SendAlgorithmWithDebugInfos) {
+	c.InheritState(h.congestion.GetCongestionWindow(), h.bytesInFlight, h.maxDatagramSize)
+	h.congestion = c
+	if h.tracer != nil && h.tracer.UpdatedMetrics != nil {
+		h.tracer.UpdatedMetrics(h.rttStats, h.congestion.GetCongestionWindow(), h.bytesInFlight, h.packetsInFlight())
+	}
+}
+
 func (h *sentPacketHandler) isAmplificationLimited() bool {
 	if h.peerAddressValidated {
 		return false
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/quic-go/quic-go/internal/congestion"
 	"github.com/quic-go/quic-go/internal/mocks"
 	"github.com/quic-go/quic-go/internal/protocol"
 	"github.com/quic-go/quic-go/internal/qerr"
@@ -106,7 +107,7 @@ var _ = Describe("SentPacketHandler", func() {
 	}
 
 	sentPacket := func(p *packet) {
-		handler.SentPacket(p.SendTime, p.PacketNumber, p.LargestAcked, p.StreamFrames, p.Frames, p.EncryptionLevel, protocol.ECNNon, p.Length, p.IsPathMTUProbePacket)
+		handler.SentPacket(p.SendTime, p.PacketNumber, p.LargestAcked, p.StreamFrames, p.Frames, p.EncryptionLevel, protocol.ECNNon, p.Length, p.IsPathMTUProbePacket, false)
 	}
 
 	expectInPacketHistory := func(expected []protocol.PacketNumber, encLevel protocol.EncryptionLevel) {
@@ -248,6 +249,26 @@ var _ = Describe("SentPacketHandler", func() {
 				Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(10)))
 			})
 
+			It("rejects ACKs for packet numbers below the first packet sent", func() {
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: 1000, EncryptionLevel: protocol.EncryptionInitial}))
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: 1001, EncryptionLevel: protocol.EncryptionInitial}))
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
+				_, err := handler.ReceivedAck(ack, protocol.EncryptionInitial, time.Now())
+				Expect(err).To(MatchError(&qerr.TransportError{
+					ErrorCode:    qerr.ProtocolViolation,
+					ErrorMessage: "received ACK for an unsent packet",
+				}))
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 999, Largest: 1001}}}
+				_, err = handler.ReceivedAck(ack, protocol.EncryptionInitial, time.Now())
+				Expect(err).To(MatchError(&qerr.TransportError{
+					ErrorCode:    qerr.ProtocolViolation,
+					ErrorMessage: "received ACK for an unsent packet",
+				}))
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1000, Largest: 1001}}}
+				_, err = handler.ReceivedAck(ack, protocol.EncryptionInitial, time.Now())
+				Expect(err).ToNot(HaveOccurred())
+			})
+
 			It("ignores repeated ACKs", func() {
 				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 0, Largest: 3}}}
 				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
@@ -412,6 +433,34 @@ var _ = Describe("SentPacketHandler", func() {
 				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 1*time.Minute, 1*time.Second))
 			})
 
+			It("doesn't take an RTT sample for a repeated ACK", func() {
+				now := time.Now()
+				getPacket(6, protocol.Encryption1RTT).SendTime = now.Add(-10 * time.Minute)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 10*time.Minute, 1*time.Second))
+				// the same ACK is received again, much later
+				_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(time.Hour))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 10*time.Minute, 1*time.Second))
+			})
+
+			It("doesn't take an RTT sample if the largest acked was already acknowledged", func() {
+				now := time.Now()
+				getPacket(5, protocol.Encryption1RTT).SendTime = now.Add(-20 * time.Minute)
+				getPacket(6, protocol.Encryption1RTT).SendTime = now.Add(-10 * time.Minute)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+				_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 10*time.Minute, 1*time.Second))
+				// This ACK newly acknowledges packet 5, but the largest acked was already acknowledged.
+				ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 5, Largest: 6}}}
+				_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now.Add(time.Minute))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler.rttStats.LatestRTT()).To(BeNumerically("~", 10*time.Minute, 1*time.Second))
+			})
+
 			It("ignores the DelayTime for Initial and Handshake packets", func() {
 				sentPacket(initialPacket(&packet{PacketNumber: 1}))
 				handler.rttStats.SetMaxAckDelay(time.Hour)
@@ -540,6 +589,23 @@ var _ = Describe("SentPacketHandler", func() {
 			})
 		})
 
+		It("doesn't track path probe packets", func() {
+			handler.ReceivedPacket(protocol.EncryptionHandshake)
+			setHandshakeConfirmed()
+			cong.EXPECT().OnPacketSent(gomock.Any(), protocol.ByteCount(42), protocol.PacketNumber(1), protocol.ByteCount(42), true)
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, Length: 42, EncryptionLevel: protocol.Encryption1RTT}))
+			timeout := handler.GetLossDetectionTimeout()
+			Expect(timeout).ToNot(BeZero())
+			handler.SentPacket(time.Now(), 2, protocol.InvalidPacketNumber, nil, []Frame{{Frame: &wire.PathChallengeFrame{}}}, protocol.Encryption1RTT, protocol.ECNNon, 1200, false, true)
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(42)))
+			Expect(handler.GetLossDetectionTimeout()).To(Equal(timeout))
+			expectInPacketHistory([]protocol.PacketNumber{1}, protocol.Encryption1RTT)
+			// the path probe packet consumed the packet number
+			cong.EXPECT().OnPacketSent(gomock.Any(), protocol.ByteCount(84), protocol.PacketNumber(3), protocol.ByteCount(42), true)
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 3, Length: 42, EncryptionLevel: protocol.Encryption1RTT}))
+			Expect(handler.appDataPackets.largestSent).To(Equal(protocol.PacketNumber(3)))
+		})
+
 		It("should call MaybeExitSlowStart and OnPacketAcked", func() {
 			rcvTime := time.Now().Add(-5 * time.Second)
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
@@ -556,6 +622,61 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("preserves the bytes in flight when switching the congestion controller", func() {
+			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, Length: 100}))
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 2, Length: 100}))
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(200)))
+			cong.EXPECT().SetMaxDatagramSize(protocol.ByteCount(1400))
+			handler.SetMaxDatagramSize(1400)
+
+			newCong := mocks.NewMockSendAlgorithmWithDebugInfos(mockCtrl)
+			cong.EXPECT().GetCongestionWindow().Return(protocol.ByteCount(12345))
+			newCong.EXPECT().InheritState(protocol.ByteCount(12345), protocol.ByteCount(200), protocol.ByteCount(1400))
+			handler.SetCongestionController(newCong)
+			newCong.EXPECT().OnPacketSent(gomock.Any(), protocol.ByteCount(300), protocol.PacketNumber(3), protocol.ByteCount(100), true)
+			sentPacket(ackElicitingPacket(&packet{PacketNumber: 3, Length: 100}))
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(300)))
+
+			gomock.InOrder(
+				newCong.EXPECT().MaybeExitSlowStart(),
+				newCong.EXPECT().OnPacketAcked(protocol.PacketNumber(1), protocol.ByteCount(100), protocol.ByteCount(300), gomock.Any()),
+				newCong.EXPECT().OnPacketAcked(protocol.PacketNumber(2), protocol.ByteCount(100), protocol.ByteCount(300), gomock.Any()),
+				newCong.EXPECT().OnPacketAcked(protocol.PacketNumber(3), protocol.ByteCount(100), protocol.ByteCount(300), gomock.Any()),
+			)
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 3}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.bytesInFlight).To(BeZero())
+		})
+
+		It("switches the congestion controller with packets in flight", func() {
+			oldCong := congestion.NewCubicSender(congestion.DefaultClock{}, handler.rttStats, protocol.InitialPacketSizeIPv4, true, nil)
+			handler.congestion = oldCong
+			for i := protocol.PacketNumber(1); i <= 30; i++ {
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: i, Length: 1000}))
+			}
+			// reduce the congestion window of the old congestion controller
+			oldCong.OnCongestionEvent(1, 1000, 30000)
+			cwnd := oldCong.GetCongestionWindow()
+
+			newCong := congestion.NewCubicSender(congestion.DefaultClock{}, handler.rttStats, protocol.InitialPacketSizeIPv4, true, nil)
+			Expect(newCong.GetCongestionWindow()).To(BeNumerically(">", 30000))
+			Expect(cwnd).To(BeNumerically("<", 30000))
+			handler.SetCongestionController(newCong)
+			Expect(newCong.GetCongestionWindow()).To(Equal(cwnd))
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(30000)))
+			// the packets sent by the old congestion controller use up the inherited congestion window
+			Expect(newCong.CanSend(handler.bytesInFlight)).To(BeFalse())
+
+			// packets sent by the old congestion controller are acknowledged to the new one
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 30}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.bytesInFlight).To(BeZero())
+			Expect(newCong.CanSend(handler.bytesInFlight)).To(BeTrue())
+		})
+
 		It("doesn't call OnPacketAcked when a retransmitted packet is acked", func() {
 			cong.EXPECT().OnPacketSent(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(2)
 			sentPacket(ackElicitingPacket(&packet{PacketNumber: 1, SendTime: time.Now().Add(-time.Hour)}))
@@ -569,7 +690,8 @@ var _ = Describe("SentPacketHandler", func() {
 			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2, Largest: 2}}}
 			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
 			Expect(err).ToNot(HaveOccurred())
-			// don't EXPECT any further calls to the congestion controller
+			// don't EXPECT any further calls to the congestion controller, except for the spurious loss
+			cong.EXPECT().OnSpuriousCongestionEvent(protocol.PacketNumber(1))
 			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 2}}}
 			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
 			Expect(err).ToNot(HaveOccurred())
@@ -691,7 +813,7 @@ var _ = Describe("SentPacketHandler", func() {
 
 	It("does nothing on OnAlarm if there are no outstanding packets", func() {
 		handler.ReceivedPacket(protocol.EncryptionHandshake)
-		Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+		Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 		Expect(handler.SendMode(time.Now())).To(Equal(SendAny))
 	})
 
@@ -740,7 +862,7 @@ var _ = Describe("SentPacketHandler", func() {
 			sentPacket(ackElicitingPacket(&packet{PacketNumber: 2, SendTime: now.Add(-time.Minute)}))
 			handler.appDataPackets.pns.(*skippingPacketNumberGenerator).next = 3
 			Expect(handler.GetLossDetectionTimeout()).To(BeTemporally("~", now.Add(-time.Minute), time.Second))
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOAppData))
 			Expect(handler.ptoCount).To(BeEquivalentTo(1))
 			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}, protocol.Encryption1RTT, time.Now())
@@ -772,7 +894,7 @@ var _ = Describe("SentPacketHandler", func() {
 			}))
 
 			// PTO timer based on the Handshake packet
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.ptoCount).To(BeEquivalentTo(1))
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOHandshake))
 			Expect(handler.GetLossDetectionTimeout()).To(Equal(sendTimeHandshake.Add(handler.rttStats.PTO(false) << 1)))
@@ -798,7 +920,7 @@ var _ = Describe("SentPacketHandler", func() {
 					},
 				},
 			}))
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOAppData))
 			sentPacket(ackElicitingPacket(&packet{PacketNumber: handler.PopPacketNumber(protocol.Encryption1RTT)}))
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOAppData))
@@ -813,7 +935,7 @@ var _ = Describe("SentPacketHandler", func() {
 				PacketNumber: handler.PopPacketNumber(protocol.Encryption1RTT),
 				SendTime:     time.Now().Add(-time.Hour),
 			}))
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOAppData))
 			sentPacket(ackElicitingPacket(&packet{PacketNumber: handler.PopPacketNumber(protocol.Encryption1RTT)}))
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOAppData))
@@ -834,14 +956,14 @@ var _ = Describe("SentPacketHandler", func() {
 			updateRTT(time.Hour)
 			Expect(handler.appDataPackets.lossTime.IsZero()).To(BeTrue())
 
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed()) // TLP
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed()) // TLP
 			Expect(handler.ptoCount).To(BeEquivalentTo(1))
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOAppData))
 			sentPacket(ackElicitingPacket(&packet{PacketNumber: handler.PopPacketNumber(protocol.Encryption1RTT)}))
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOAppData))
 			sentPacket(ackElicitingPacket(&packet{PacketNumber: handler.PopPacketNumber(protocol.Encryption1RTT)}))
 
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed()) // PTO
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed()) // PTO
 			Expect(handler.ptoCount).To(BeEquivalentTo(2))
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOAppData))
 			sentPacket(ackElicitingPacket(&packet{PacketNumber: handler.PopPacketNumber(protocol.Encryption1RTT)}))
@@ -859,7 +981,7 @@ var _ = Describe("SentPacketHandler", func() {
 			updateRTT(time.Hour)
 			Expect(handler.initialPackets.lossTime.IsZero()).To(BeTrue())
 
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOInitial))
 			sentPacket(initialPacket(&packet{PacketNumber: 3}))
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOInitial))
@@ -872,12 +994,12 @@ var _ = Describe("SentPacketHandler", func() {
 			handler.ReceivedPacket(protocol.EncryptionHandshake)
 			sentPacket(ackElicitingPacket(&packet{PacketNumber: handler.PopPacketNumber(protocol.Encryption1RTT)}))
 			updateRTT(time.Hour)
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.GetLossDetectionTimeout()).To(BeZero())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendAny))
 			setHandshakeConfirmed()
 			Expect(handler.GetLossDetectionTimeout()).ToNot(BeZero())
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOAppData))
 		})
 
@@ -887,7 +1009,7 @@ var _ = Describe("SentPacketHandler", func() {
 			pn := handler.PopPacketNumber(protocol.Encryption1RTT)
 			sentPacket(ackElicitingPacket(&packet{PacketNumber: pn, SendTime: time.Now().Add(-time.Hour)}))
 			updateRTT(time.Second)
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOAppData))
 			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: pn, Largest: pn}}}
 			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, time.Now())
@@ -902,8 +1024,8 @@ var _ = Describe("SentPacketHandler", func() {
 				SendTime:     time.Now().Add(-time.Hour),
 			}))
 			updateRTT(time.Second)
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 		})
 
 		It("doesn't set the PTO timer for Path MTU probe packets", func() {
@@ -1017,7 +1139,7 @@ var _ = Describe("SentPacketHandler", func() {
 			// No packets are outstanding at this point.
 			// Make sure that a probe packet is sent.
 			Expect(handler.GetLossDetectionTimeout()).ToNot(BeZero())
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOInitial))
 
 			// send a single packet to unblock the server
@@ -1050,7 +1172,7 @@ var _ = Describe("SentPacketHandler", func() {
 			sentPacket(handshakePacketNonAckEliciting(&packet{PacketNumber: 1}))
 			handler.DropPackets(protocol.EncryptionInitial) // sending a Handshake packet drops the Initial packet number space
 			Expect(handler.GetLossDetectionTimeout()).ToNot(BeZero())
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOHandshake))
 
 			// Now receive an ACK for this packet, and send another one.
@@ -1067,7 +1189,7 @@ var _ = Describe("SentPacketHandler", func() {
 		It("doesn't send a packet to unblock the server after handshake confirmation, even if no Handshake ACK was received", func() {
 			sentPacket(handshakePacket(&packet{PacketNumber: 1}))
 			Expect(handler.GetLossDetectionTimeout()).ToNot(BeZero())
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOHandshake))
 			// confirm the handshake
 			handler.DropPackets(protocol.EncryptionHandshake)
@@ -1097,7 +1219,7 @@ var _ = Describe("SentPacketHandler", func() {
 			sentPacket(initialPacket(&packet{PacketNumber: 1, SendTime: now.Add(-time.Minute)}))
 			sentPacket(initialPacket(&packet{PacketNumber: 2, SendTime: now.Add(-time.Minute)}))
 			Expect(handler.GetLossDetectionTimeout()).To(BeTemporally("~", now.Add(-time.Minute), time.Second))
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOInitial))
 			Expect(handler.ptoCount).To(BeEquivalentTo(1))
 			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 1}}}, protocol.EncryptionInitial, time.Now())
@@ -1118,6 +1240,40 @@ var _ = Describe("SentPacketHandler", func() {
 			expectInPacketHistory([]protocol.PacketNumber{4, 5}, protocol.Encryption1RTT)
 			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
 		})
+
+		It("detects spurious losses", func() {
+			now := time.Now()
+			for i := protocol.PacketNumber(1); i <= 6; i++ {
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: i}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			Expect(handler.appDataPackets.lostPackets).To(HaveLen(3))
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(2)))
+			// now receive an ACK for packets 1 and 2, which were already declared lost
+			ack = &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 6, Largest: 6}, {Smallest: 1, Largest: 2}}}
+			_, err = handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.appDataPackets.lostPackets).To(Equal([]protocol.PacketNumber{3}))
+			// the packets are neither acknowledged again, nor are their bytes removed from bytes_in_flight again
+			Expect(handler.bytesInFlight).To(Equal(protocol.ByteCount(2)))
+			Expect(lostPackets).To(Equal([]protocol.PacketNumber{1, 2, 3}))
+			expectInPacketHistory([]protocol.PacketNumber{4, 5}, protocol.Encryption1RTT)
+		})
+
+		It("limits the number of lost packets tracked for spurious loss detection", func() {
+			now := time.Now()
+			for i := protocol.PacketNumber(1); i <= 2*maxTrackedLostPackets; i++ {
+				sentPacket(ackElicitingPacket(&packet{PacketNumber: i}))
+			}
+			ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 2 * maxTrackedLostPackets, Largest: 2 * maxTrackedLostPackets}}}
+			_, err := handler.ReceivedAck(ack, protocol.Encryption1RTT, now)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.appDataPackets.lostPackets).To(HaveLen(maxTrackedLostPackets))
+			Expect(handler.appDataPackets.lostPackets[0]).To(BeNumerically(">", 1))
+		})
 	})
 
 	Context("Delay-based loss detection", func() {
@@ -1151,11 +1307,16 @@ var _ = Describe("SentPacketHandler", func() {
 
 			// Packet 1 should be considered lost (1+1/8) RTTs after it was sent.
 			Expect(handler.GetLossDetectionTimeout().Sub(getPacket(1, protocol.Encryption1RTT).SendTime)).To(Equal(time.Second * 9 / 8))
+			// the timer is set for time threshold loss detection
+			Expect(handler.GetLossDetectionTimeout()).To(Equal(handler.appDataPackets.lossTime))
 			Expect(handler.SendMode(time.Now())).To(Equal(SendAny))
 
 			expectInPacketHistory([]protocol.PacketNumber{1, 3}, protocol.Encryption1RTT)
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			expectInPacketHistory([]protocol.PacketNumber{3}, protocol.Encryption1RTT)
+			// packet 3 is still outstanding, so the PTO timer is set
+			Expect(handler.appDataPackets.lossTime).To(BeZero())
+			Expect(handler.GetLossDetectionTimeout()).ToNot(BeZero())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendAny))
 		})
 
@@ -1177,7 +1338,7 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(handler.SendMode(time.Now())).To(Equal(SendAny))
 
 			expectInPacketHistory([]protocol.PacketNumber{1, 3}, protocol.EncryptionInitial)
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			expectInPacketHistory([]protocol.PacketNumber{3}, protocol.EncryptionInitial)
 			Expect(handler.SendMode(time.Now())).To(Equal(SendAny))
 		})
@@ -1202,7 +1363,7 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(mtuPacketDeclaredLost).To(BeFalse())
 			Expect(handler.GetLossDetectionTimeout()).ToNot(BeZero())
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(mtuPacketDeclaredLost).To(BeTrue())
 			Expect(handler.GetLossDetectionTimeout()).To(BeZero())
 		})
@@ -1289,7 +1450,7 @@ var _ = Describe("SentPacketHandler", func() {
 			sentPacket(handshakePacket(&packet{PacketNumber: 1, SendTime: now.Add(-time.Minute)}))
 			sentPacket(handshakePacket(&packet{PacketNumber: 2, SendTime: now.Add(-time.Minute)}))
 			Expect(handler.GetLossDetectionTimeout()).To(BeTemporally("~", now.Add(-time.Minute), time.Second))
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOHandshake))
 			Expect(handler.ptoCount).To(BeEquivalentTo(1))
 			handler.DropPackets(protocol.EncryptionHandshake)
@@ -1418,7 +1579,7 @@ var _ = Describe("SentPacketHandler", func() {
 				EncryptionLevel: protocol.EncryptionInitial,
 				SendTime:        now,
 			}))
-			Expect(handler.OnLossDetectionTimeout()).To(Succeed())
+			Expect(handler.OnLossDetectionTimeout(time.Now())).To(Succeed())
 			Expect(handler.SendMode(time.Now())).To(Equal(SendPTOInitial))
 			sentPacket(ackElicitingPacket(&packet{
 				PacketNumber:    43,
@@ -1450,28 +1611,28 @@ var _ = Describe("SentPacketHandler", func() {
 
 		It("informs about sent packets", func() {
 			// Check that only 1-RTT packets are reported
-			handler.SentPacket(time.Now(), 100, -1, nil, nil, protocol.EncryptionInitial, protocol.ECT1, 1200, false)
-			handler.SentPacket(time.Now(), 101, -1, nil, nil, protocol.EncryptionHandshake, protocol.ECT0, 1200, false)
-			handler.SentPacket(time.Now(), 102, -1, nil, nil, protocol.Encryption0RTT, protocol.ECNCE, 1200, false)
+			handler.SentPacket(time.Now(), 100, -1, nil, nil, protocol.EncryptionInitial, protocol.ECT1, 1200, false, false)
+			handler.SentPacket(time.Now(), 101, -1, nil, nil, protocol.EncryptionHandshake, protocol.ECT0, 1200, false, false)
+			handler.SentPacket(time.Now(), 102, -1, nil, nil, protocol.Encryption0RTT, protocol.ECNCE, 1200, false, false)
 
 			ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(103), protocol.ECT1)
-			handler.SentPacket(time.Now(), 103, -1, nil, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false)
+			handler.SentPacket(time.Now(), 103, -1, nil, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false, false)
 		})
 
 		It("informs about sent packets", func() {
 			// Check that only 1-RTT packets are reported
-			handler.SentPacket(time.Now(), 100, -1, nil, nil, protocol.EncryptionInitial, protocol.ECT1, 1200, false)
-			handler.SentPacket(time.Now(), 101, -1, nil, nil, protocol.EncryptionHandshake, protocol.ECT0, 1200, false)
-			handler.SentPacket(time.Now(), 102, -1, nil, nil, protocol.Encryption0RTT, protocol.ECNCE, 1200, false)
+			handler.SentPacket(time.Now(), 100, -1, nil, nil, protocol.EncryptionInitial, protocol.ECT1, 1200, false, false)
+			handler.SentPacket(time.Now(), 101, -1, nil, nil, protocol.EncryptionHandshake, protocol.ECT0, 1200, false, false)
+			handler.SentPacket(time.Now(), 102, -1, nil, nil, protocol.Encryption0RTT, protocol.ECNCE, 1200, false, false)
 
 			ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(103), protocol.ECT1)
-			handler.SentPacket(time.Now(), 103, -1, nil, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false)
+			handler.SentPacket(time.Now(), 103, -1, nil, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false, false)
 		})
 
 		It("informs about lost packets", func() {
 			for i := 10; i < 20; i++ {
 				ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(i), protocol.ECT1)
-				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false)
+				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false, false)
 			}
 			cong.EXPECT().OnCongestionEvent(gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
 			ecnHandler.EXPECT().LostPacket(protocol.PacketNumber(10))
@@ -1484,13 +1645,13 @@ var _ = Describe("SentPacketHandler", func() {
 
 		It("processes ACKs", func() {
 			// Check that we only care about 1-RTT packets.
-			handler.SentPacket(time.Now(), 100, -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.EncryptionInitial, protocol.ECT1, 1200, false)
+			handler.SentPacket(time.Now(), 100, -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.EncryptionInitial, protocol.ECT1, 1200, false, false)
 			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Largest: 100, Smallest: 100}}}, protocol.EncryptionInitial, time.Now())
 			Expect(err).ToNot(HaveOccurred())
 
 			for i := 10; i < 20; i++ {
 				ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(i), protocol.ECT1)
-				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false)
+				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false, false)
 			}
 			ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(1), int64(2), int64(3)).DoAndReturn(func(packets []*packet, _, _, _ int64) bool {
 				Expect(packets).To(HaveLen(5))
@@ -1516,7 +1677,7 @@ var _ = Describe("SentPacketHandler", func() {
 		It("ignores reordered ACKs", func() {
 			for i := 10; i < 20; i++ {
 				ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(i), protocol.ECT1)
-				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false)
+				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false, false)
 			}
 			ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(1), int64(2), int64(3)).DoAndReturn(func(packets []*packet, _, _, _ int64) bool {
 				Expect(packets).To(HaveLen(2))
@@ -1543,7 +1704,7 @@ var _ = Describe("SentPacketHandler", func() {
 		It("ignores ACKs that don't increase the largest acked", func() {
 			for i := 10; i < 20; i++ {
 				ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(i), protocol.ECT1)
-				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false)
+				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT1, 1200, false, false)
 			}
 			ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(1), int64(2), int64(3)).DoAndReturn(func(packets []*packet, _, _, _ int64) bool {
 				Expect(packets).To(HaveLen(1))
@@ -1565,15 +1726,100 @@ var _ = Describe("SentPacketHandler", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("errors when the ECN counts decrease", func() {
+			for i := 10; i < 20; i++ {
+				ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(i), protocol.ECT0)
+				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT0, 1200, false, false)
+			}
+			ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(3), int64(0), int64(1))
+			_, err := handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Largest: 13, Smallest: 10}},
+				ECT0:      3,
+				ECNCE:     1,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Largest: 15, Smallest: 10}},
+				ECT0:      2,
+				ECNCE:     3,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.ProtocolViolation,
+				ErrorMessage: "decreased ECN counts",
+			}))
+		})
+
+		It("passes ACKs without ECN counts to the ECN handler after ECN counts were reported", func() {
+			for i := 10; i < 20; i++ {
+				ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(i), protocol.ECT0)
+				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT0, 1200, false, false)
+			}
+			ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(3), int64(0), int64(0))
+			_, err := handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Largest: 13, Smallest: 10}},
+				ECT0:      3,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			// it's the ECN handler's job to disable ECN
+			ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(0), int64(0), int64(0))
+			_, err = handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Largest: 15, Smallest: 10}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			// ECN counts are still compared to the last ECN counts reported
+			_, err = handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Largest: 17, Smallest: 10}},
+				ECT0:      2,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.ProtocolViolation,
+				ErrorMessage: "decreased ECN counts",
+			}))
+		})
+
+		It("doesn't error when a reordered ACK carries smaller ECN counts", func() {
+			for i := 10; i < 20; i++ {
+				ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(i), protocol.ECT0)
+				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT0, 1200, false, false)
+			}
+			ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(4), int64(0), int64(0))
+			_, err := handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Largest: 13, Smallest: 10}},
+				ECT0:      4,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = handler.ReceivedAck(&wire.AckFrame{
+				AckRanges: []wire.AckRange{{Largest: 12, Smallest: 10}},
+				ECT0:      3,
+			}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+		})
+
 		It("informs the congestion controller about CE events", func() {
 			for i := 10; i < 20; i++ {
 				ecnHandler.EXPECT().SentPacket(protocol.PacketNumber(i), protocol.ECT0)
-				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT0, 1200, false)
+				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECT0, 1200, false, false)
 			}
 			ecnHandler.EXPECT().HandleNewlyAcked(gomock.Any(), int64(0), int64(0), int64(0)).Return(true)
 			cong.EXPECT().OnCongestionEvent(protocol.PacketNumber(15), gomock.Any(), gomock.Any())
 			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Largest: 15, Smallest: 10}}}, protocol.Encryption1RTT, time.Now())
 			Expect(err).ToNot(HaveOccurred())
 		})
+
+		It("stops using ECN if the path bleaches the ECN markings", func() {
+			handler = newSentPacketHandler(42, protocol.InitialPacketSizeIPv4, utils.NewRTTStats(), false, true, perspective, nil, utils.DefaultLogger)
+			for i := 0; i < 5; i++ {
+				ecn := handler.ECNMode(true)
+				Expect(ecn).To(Equal(protocol.ECT0))
+				handler.SentPacket(time.Now(), protocol.PacketNumber(i), -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, ecn, 1200, false, false)
+			}
+			// the peer acknowledges all packets, but doesn't report any ECN counts
+			_, err := handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Largest: 4, Smallest: 0}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.ECNMode(true)).To(Equal(protocol.ECNNon))
+			// ECN stays disabled for the rest of the connection
+			handler.SentPacket(time.Now(), 5, -1, []StreamFrame{{Frame: &streamFrame}}, nil, protocol.Encryption1RTT, protocol.ECNNon, 1200, false, false)
+			_, err = handler.ReceivedAck(&wire.AckFrame{AckRanges: []wire.AckRange{{Largest: 5, Smallest: 0}}}, protocol.Encryption1RTT, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler.ECNMode(true)).To(Equal(protocol.ECNNon))
+		})
 	})
 })
@@ -37,6 +37,16 @@ type cubicSender struct {
 	// Track the largest packet number outstanding when a CWND cutback occurs.
 	largestSentAtLastCutback protocol.PacketNumber
 
+	// State saved when the last CWND cutback occurred.
+	// This allows undoing the cutback if all losses that happened since then turn out to be spurious.
+	cutbackPacketNumber                   protocol.PacketNumber // the packet whose loss caused the cutback
+	numLostSinceCutback                   int
+	congestionWindowBeforeCutback         protocol.ByteCount
+	slowStartThresholdBeforeCutback       protocol.ByteCount
+	largestSentAtCutbackBeforeLastCutback protocol.PacketNumber
+	cubicBeforeCutback                    Cubic // the cubic state, incl. the epoch and the last max congestion window
+	exitedSlowstartBeforeCutback          bool
+
 	// Whether the last loss event caused us to exit slowstart.
 	// Used for stats collection of slowstartPacketsLost
 	lastCutbackExitedSlowstart bool
@@ -97,6 +107,7 @@ func newCubicSender(
 		largestSentPacketNumber:    protocol.InvalidPacketNumber,
 		largestAckedPacketNumber:   protocol.InvalidPacketNumber,
 		largestSentAtLastCutback:   protocol.InvalidPacketNumber,
+		cutbackPacketNumber:        protocol.InvalidPacketNumber,
 		initialCongestionWindow:    initialCongestionWindow,
 		initialMaxCongestionWindow: initialMaxCongestionWindow,
 		congestionWindow:           initialCongestionWindow,
@@ -192,8 +203,19 @@ func (c *cubicSender) OnCongestionEvent(packetNumber protocol.PacketNumber, lost
 	// TCP NewReno (RFC6582) says that once a loss occurs, any losses in packets
 	// already sent should be treated as a single loss event, since it's expected.
 	if packetNumber <= c.largestSentAtLastCutback {
+		if c.cutbackPacketNumber != protocol.InvalidPacketNumber && packetNumber >= c.cutbackPacketNumber {
+			c.numLostSinceCutback++
+		}
 		return
 	}
+	c.cutbackPacketNumber = packetNumber
+	c.numLostSinceCutback = 1
+	c.congestionWindowBeforeCutback = c.congestionWindow
+	c.slowStartThresholdBeforeCutback = c.slowStartThreshold
+	c.largestSentAtCutbackBeforeLastCutback = c.largestSentAtLastCutback
+	c.cubicBeforeCutback = *c.cubic
+	c.exitedSlowstartBeforeCutback = c.lastCutbackExitedSlowstart
+
 	c.lastCutbackExitedSlowstart = c.InSlowStart()
 	c.maybeTraceStateChange(logging.CongestionStateRecovery)
 
@@ -212,6 +234,30 @@ func (c *cubicSender) OnCongestionEvent(packetNumber protocol.PacketNumber, lost
 	c.numAckedPackets = 0
 }
 
+// OnSpuriousCongestionEvent undoes the last CWND cutback,
+// once all packets declared lost since that cutback have been acknowledged.
+func (c *cubicSender) OnSpuriousCongestionEvent(packetNumber protocol.PacketNumber) {
+	if c.cutbackPacketNumber == protocol.InvalidPacketNumber ||
+		packetNumber < c.cutbackPacketNumber || packetNumber > c.largestSentAtLastCutback {
+		return
+	}
+	c.numLostSinceCutback--
+	if c.numLostSinceCutback > 0 {
+		return
+	}
+	c.congestionWindow = max(c.congestionWindow, c.congestionWindowBeforeCutback)
+	c.slowStartThreshold = max(c.slowStartThreshold, c.slowStartThresholdBeforeCutback)
+	c.largestSentAtLastCutback = c.largestSentAtCutbackBeforeLastCutback
+	*c.cubic = c.cubicBeforeCutback
+	c.lastCutbackExitedSlowstart = c.exitedSlowstartBeforeCutback
+	c.cutbackPacketNumber = protocol.InvalidPacketNumber
+	if c.InSlowStart() {
+		c.maybeTraceStateChange(logging.CongestionStateSlowStart)
+	} else {
+		c.maybeTraceStateChange(logging.CongestionStateCongestionAvoidance)
+	}
+}
+
 // Called when we receive an ack. Normal TCP tracks how many packets one ack
 // represents, but quic has a separate ack for each packet.
 func (c *cubicSender) maybeIncreaseCwnd(
@@ -273,6 +319,7 @@ func (c *cubicSender) BandwidthEstimate() Bandwidth {
 // OnRetransmissionTimeout is called on an retransmission timeout
 func (c *cubicSender) OnRetransmissionTimeout(packetsRetransmitted bool) {
 	c.largestSentAtLastCutback = protocol.InvalidPacketNumber
+	c.cutbackPacketNumber = protocol.InvalidPacketNumber
 	if !packetsRetransmitted {
 		return
 	}
@@ -288,6 +335,7 @@ func (c *cubicSender) OnConnectionMigration() {
 	c.largestSentPacketNumber = protocol.InvalidPacketNumber
 	c.largestAckedPacketNumber = protocol.InvalidPacketNumber
 	c.largestSentAtLastCutback = protocol.InvalidPacketNumber
+	c.cutbackPacketNumber = protocol.InvalidPacketNumber
 	c.lastCutbackExitedSlowstart = false
 	c.cubic.Reset()
 	c.numAckedPackets = 0
@@ -303,10 +351,28 @@ func (c *cubicSender) maybeTraceStateChange(new logging.CongestionState) {
 	c.lastState = new
 }
 
+// InheritState takes over the congestion window of the previous congestion controller.
+// The packets in flight were sent by the previous congestion controller,
+// and are accounted for in the pacing budget, as if they had just been sent.
+func (c *cubicSender) InheritState(congestionWindow, bytesInFlight, maxDatagramSize protocol.ByteCount) {
+	// The inherited datagram size might be smaller than ours, which SetMaxDatagramSize doesn't allow.
+	c.setMaxDatagramSize(maxDatagramSize)
+	c.congestionWindow = min(max(congestionWindow, c.minCongestionWindow()), c.maxCongestionWindow())
+	if bytesInFlight > 0 {
+		c.pacer.SentPacket(c.clock.Now(), bytesInFlight)
+	}
+}
+
 func (c *cubicSender) SetMaxDatagramSize(s protocol.ByteCount) {
 	if s < c.maxDatagramSize {
 		panic(fmt.Sprintf("congestion BUG: decreased max datagram size from %d to %d", c.maxDatagramSize, s))
 	}
+	c.setMaxDatagramSize(s)
+}
+
+// setMaxDatagramSize updates the maximum datagram size of the congestion controller and the pacer.
+// Unlike SetMaxDatagramSize, it allows decreasing the datagram size.
+func (c *cubicSender) setMaxDatagramSize(s protocol.ByteCount) {
 	cwndIsMinCwnd := c.congestionWindow == c.minCongestionWindow()
 	c.maxDatagramSize = s
 	if cwndIsMinCwnd {
@@ -372,6 +372,89 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(postLossWindow).To(BeNumerically(">", sender.GetCongestionWindow()))
 	})
 
+	It("undoes the cutback when a loss turns out to be spurious", func() {
+		SendAvailableSendWindow()
+		AckNPackets(2)
+		SendAvailableSendWindow()
+		preLossWindow := sender.GetCongestionWindow()
+		preLossThreshold := sender.slowStartThreshold
+		lostPacket := ackedPacketNumber + 1
+		LosePacket(lostPacket)
+		LosePacket(lostPacket + 2)
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<", preLossWindow))
+		Expect(sender.InRecovery()).To(BeTrue())
+		// only one of the two losses was spurious
+		sender.OnSpuriousCongestionEvent(lostPacket)
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<", preLossWindow))
+		// now all losses were spurious
+		sender.OnSpuriousCongestionEvent(lostPacket + 2)
+		Expect(sender.GetCongestionWindow()).To(Equal(preLossWindow))
+		Expect(sender.slowStartThreshold).To(Equal(preLossThreshold))
+		Expect(sender.InRecovery()).To(BeFalse())
+	})
+
+	It("continues growing the congestion window as if the spurious loss never happened", func() {
+		newSender := func() *cubicSender {
+			return newCubicSender(&clock, rttStats, false, protocol.InitialPacketSizeIPv4, initialCongestionWindowPackets*maxDatagramSize, MaxCongestionWindow, nil)
+		}
+		// sender experiences a spurious loss, otherSender doesn't
+		sender = newSender()
+		otherSender := newSender()
+		rttStats.UpdateRTT(60*time.Millisecond, 0, clock.Now())
+		var pn protocol.PacketNumber
+		sendAndAck := func(n int) {
+			first := pn + 1
+			for i := 0; i < n; i++ {
+				pn++
+				sender.OnPacketSent(clock.Now(), 0, pn, maxDatagramSize, true)
+				otherSender.OnPacketSent(clock.Now(), 0, pn, maxDatagramSize, true)
+			}
+			for p := first; p <= pn; p++ {
+				clock.Advance(100 * time.Millisecond)
+				sender.OnPacketAcked(p, maxDatagramSize, sender.GetCongestionWindow(), clock.Now())
+				otherSender.OnPacketAcked(p, maxDatagramSize, otherSender.GetCongestionWindow(), clock.Now())
+			}
+		}
+		// exit slow start and recovery
+		sendAndAck(1)
+		sender.OnCongestionEvent(1, maxDatagramSize, 10*maxDatagramSize)
+		otherSender.OnCongestionEvent(1, maxDatagramSize, 10*maxDatagramSize)
+		sendAndAck(50)
+		Expect(sender.InSlowStart()).To(BeFalse())
+		Expect(sender.InRecovery()).To(BeFalse())
+		Expect(sender.GetCongestionWindow()).To(Equal(otherSender.GetCongestionWindow()))
+
+		// lose a packet, and undo the cutback before it is acknowledged
+		sender.OnPacketSent(clock.Now(), 0, pn+1, maxDatagramSize, true)
+		otherSender.OnPacketSent(clock.Now(), 0, pn+1, maxDatagramSize, true)
+		sender.OnCongestionEvent(pn+1, maxDatagramSize, sender.GetCongestionWindow())
+		Expect(sender.GetCongestionWindow()).To(BeNumerically("<", otherSender.GetCongestionWindow()))
+		sender.OnSpuriousCongestionEvent(pn + 1)
+		clock.Advance(100 * time.Millisecond)
+		sender.OnPacketAcked(pn+1, maxDatagramSize, sender.GetCongestionWindow(), clock.Now())
+		otherSender.OnPacketAcked(pn+1, maxDatagramSize, otherSender.GetCongestionWindow(), clock.Now())
+		pn++
+
+		cwnd := sender.GetCongestionWindow()
+		for i := 0; i < 10; i++ {
+			sendAndAck(10)
+			Expect(sender.GetCongestionWindow()).To(Equal(otherSender.GetCongestionWindow()))
+		}
+		Expect(sender.GetCongestionWindow()).To(BeNumerically(">", cwnd))
+	})
+
+	It("doesn't undo the cutback after a retransmission timeout", func() {
+		SendAvailableSendWindow()
+		AckNPackets(2)
+		SendAvailableSendWindow()
+		lostPacket := ackedPacketNumber + 1
+		LosePacket(lostPacket)
+		sender.OnRetransmissionTimeout(true)
+		cwnd := sender.GetCongestionWindow()
+		sender.OnSpuriousCongestionEvent(lostPacket)
+		Expect(sender.GetCongestionWindow()).To(Equal(cwnd))
+	})
+
 	It("1 connection congestion avoidance at end of recovery", func() {
 		// Ack 10 packets in 5 acks to raise the CWND to 20.
 		const numberOfAcks = 5
@@ -469,6 +552,37 @@ var _ = Describe("Cubic Sender", func() {
 		Expect(sender.GetCongestionWindow()).To(Equal(initialMaxCongestionWindow))
 	})
 
+	It("inherits the state of a previous congestion controller", func() {
+		clock.Advance(time.Hour)
+		Expect(sender.HasPacingBudget(clock.Now())).To(BeTrue())
+		sender.InheritState(5*1400, 20*1400, 1400)
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(5 * 1400)))
+		Expect(sender.maxDatagramSize).To(Equal(protocol.ByteCount(1400)))
+		// the bytes in flight use up the pacing budget
+		Expect(sender.HasPacingBudget(clock.Now())).To(BeFalse())
+	})
+
+	It("clamps the inherited congestion window", func() {
+		sender.InheritState(1, 0, maxDatagramSize)
+		Expect(sender.GetCongestionWindow()).To(Equal(sender.minCongestionWindow()))
+		sender.InheritState(protocol.MaxByteCount, 0, maxDatagramSize)
+		Expect(sender.GetCongestionWindow()).To(Equal(sender.maxCongestionWindow()))
+	})
+
+	It("inherits a smaller maximum datagram size", func() {
+		const packetSize = initialMaxDatagramSize + 100
+		sender.SetMaxDatagramSize(packetSize)
+		Expect(func() { sender.InheritState(0, 0, initialMaxDatagramSize) }).ToNot(Panic())
+		Expect(sender.maxDatagramSize).To(Equal(protocol.ByteCount(initialMaxDatagramSize)))
+		Expect(sender.GetCongestionWindow()).To(Equal(protocol.ByteCount(minCongestionWindowPackets * initialMaxDatagramSize)))
+		// the pacer uses the smaller datagram size as well
+		clock.Advance(time.Hour)
+		Expect(sender.pacer.Budget(clock.Now())).To(Equal(protocol.ByteCount(maxBurstSizePackets * initialMaxDatagramSize)))
+		// it's possible to increase the datagram size again
+		sender.SetMaxDatagramSize(packetSize)
+		Expect(sender.pacer.Budget(clock.Now())).To(Equal(protocol.ByteCount(maxBurstSizePackets * packetSize)))
+	})
+
 	It("doesn't allow reductions of the maximum packet size", func() {
 		Expect(func() { sender.SetMaxDatagramSize(initialMaxDatagramSize - 1) }).To(Panic())
 	})
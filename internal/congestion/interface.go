@@ -15,8 +15,14 @@ type SendAlgorithm interface {
 	MaybeExitSlowStart()
 	OnPacketAcked(number protocol.PacketNumber, ackedBytes protocol.ByteCount, priorInFlight protocol.ByteCount, eventTime time.Time)
 	OnCongestionEvent(number protocol.PacketNumber, lostBytes protocol.ByteCount, priorInFlight protocol.ByteCount)
+	// OnSpuriousCongestionEvent is called when a packet that was reported lost is acknowledged after all.
+	OnSpuriousCongestionEvent(number protocol.PacketNumber)
 	OnRetransmissionTimeout(packetsRetransmitted bool)
 	SetMaxDatagramSize(protocol.ByteCount)
+	// InheritState is called when the congestion controller replaces another congestion controller mid-connection.
+	// It is called with the congestion window of the previous congestion controller,
+	// the number of bytes in flight and the current maximum datagram size.
+	InheritState(congestionWindow, bytesInFlight, maxDatagramSize protocol.ByteCount)
 }
 
 // A SendAlgorithmWithDebugInfos is a SendAlgorithm that exposes some debug infos
@@ -101,6 +101,39 @@ var _ = Describe("Pacer", func() {
 		Expect(p.Budget(t.Add(5 * t2.Sub(t)))).To(BeEquivalentTo(5 * packetSize))
 	})
 
+	It("makes up for a timer that fired late", func() {
+		t := time.Now()
+		sendBurst(t)
+		interval := time.Second / packetsPerSecond
+		// the timer fires 2.5 intervals late
+		t2 := p.TimeUntilSend().Add(5 * interval / 2)
+		for i := 0; i < 3; i++ {
+			Expect(p.Budget(t2)).To(BeNumerically(">=", initialMaxDatagramSize))
+			p.SentPacket(t2, initialMaxDatagramSize)
+		}
+		Expect(p.Budget(t2)).To(BeEquivalentTo(initialMaxDatagramSize / 2))
+		Expect(p.TimeUntilSend()).To(BeTemporally("~", t2.Add(interval/2), time.Nanosecond))
+	})
+
+	It("doesn't reduce the sending rate when timers fire late", func() {
+		t := time.Now()
+		sendBurst(t)
+		start := t
+		interval := time.Second / packetsPerSecond
+		var sent int
+		for t.Sub(start) < 10*time.Second {
+			// simulate scheduling delays of up to one interval
+			t = p.TimeUntilSend().Add(time.Duration(rand.Int63n(int64(interval))))
+			for p.Budget(t) >= initialMaxDatagramSize {
+				p.SentPacket(t, initialMaxDatagramSize)
+				sent++
+			}
+		}
+		// The last timer might have fired up to one interval late.
+		// If the pacer didn't make up for late timers, we'd lose half an interval per packet on average.
+		Expect(sent).To(BeNumerically("~", t.Sub(start).Seconds()*packetsPerSecond, 5))
+	})
+
 	It("has enough budget for at least one packet when the timer expires", func() {
 		t := time.Now()
 		sendBurst(t)
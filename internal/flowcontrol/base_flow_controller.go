@@ -1,6 +1,7 @@
 package flowcontrol
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -32,6 +33,43 @@ type baseFlowController struct {
 	logger utils.Logger
 }
 
+// State is a snapshot of the state of a flow controller.
+// It is only intended to be used for debugging.
+type State struct {
+	// for sending data
+	BytesSent  protocol.ByteCount // the highest offset sent
+	SendWindow protocol.ByteCount // the flow control limit imposed by the peer
+	Blocked    bool               // true if all data up to the SendWindow has been sent
+
+	// for receiving data
+	BytesRead       protocol.ByteCount
+	HighestReceived protocol.ByteCount
+	ReceiveWindow   protocol.ByteCount // the flow control limit advertised to the peer
+}
+
+func (s State) String() string {
+	str := fmt.Sprintf("sent: %d (window: %d), received: %d (read: %d, window: %d)", s.BytesSent, s.SendWindow, s.HighestReceived, s.BytesRead, s.ReceiveWindow)
+	if s.Blocked {
+		str += ", blocked"
+	}
+	return str
+}
+
+// State returns the current state of the flow controller.
+func (c *baseFlowController) State() State {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return State{
+		BytesSent:       c.bytesSent,
+		SendWindow:      c.sendWindow,
+		Blocked:         c.sendWindowSize() == 0,
+		BytesRead:       c.bytesRead,
+		HighestReceived: c.highestReceived,
+		ReceiveWindow:   c.receiveWindow,
+	}
+}
+
 // IsNewlyBlocked says if it is newly blocked by flow control.
 // For every offset, it only returns true once.
 // If it is blocked, the offset is returned.
@@ -89,6 +89,27 @@ var _ = Describe("Base Flow controller", func() {
 		})
 	})
 
+	It("reports its state", func() {
+		controller.sendWindow = 100
+		controller.AddBytesSent(60)
+		controller.receiveWindow = 1000
+		controller.highestReceived = 400
+		controller.bytesRead = 300
+		state := controller.State()
+		Expect(state).To(Equal(State{
+			BytesSent:       60,
+			SendWindow:      100,
+			BytesRead:       300,
+			HighestReceived: 400,
+			ReceiveWindow:   1000,
+		}))
+		Expect(state.String()).To(Equal("sent: 60 (window: 100), received: 400 (read: 300, window: 1000)"))
+		controller.AddBytesSent(40)
+		state = controller.State()
+		Expect(state.Blocked).To(BeTrue())
+		Expect(state.String()).To(HaveSuffix(", blocked"))
+	})
+
 	Context("receive flow control", func() {
 		var (
 			receiveWindow     protocol.ByteCount = 10000
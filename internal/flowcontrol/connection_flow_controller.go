@@ -99,6 +99,9 @@ func (c *connectionFlowController) EnsureMinimumWindowSize(inc protocol.ByteCoun
 // Reset rests the flow controller. This happens when 0-RTT is rejected.
 // All stream data is invalidated, it's if we had never opened a stream and never sent any data.
 // At that point, we only have sent stream data, but we didn't have the keys to open 1-RTT keys yet.
+// The send window is reset as well: The server might have reduced its limits,
+// so the window remembered from the session ticket doesn't apply to 1-RTT data.
+// It is set again once the server's transport parameters are applied.
 func (c *connectionFlowController) Reset() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -107,6 +110,7 @@ func (c *connectionFlowController) Reset() error {
 		return errors.New("flow controller reset after reading data")
 	}
 	c.bytesSent = 0
+	c.sendWindow = 0
 	c.lastBlockedAt = 0
 	return nil
 }
@@ -167,9 +167,25 @@ var _ = Describe("Connection Flow controller", func() {
 			controller.AddBytesSent(1000)
 			Expect(controller.SendWindowSize()).To(Equal(initialWindow - 1000))
 			Expect(controller.Reset()).To(Succeed())
+			Expect(controller.SendWindowSize()).To(BeZero())
+			controller.UpdateSendWindow(initialWindow)
 			Expect(controller.SendWindowSize()).To(Equal(initialWindow))
 		})
 
+		It("uses a smaller send window after resetting", func() {
+			controller.UpdateSendWindow(2000)
+			controller.AddBytesSent(1500)
+			Expect(controller.Reset()).To(Succeed())
+			// the server reduced its limit
+			controller.UpdateSendWindow(1000)
+			Expect(controller.SendWindowSize()).To(Equal(protocol.ByteCount(1000)))
+			controller.AddBytesSent(1000)
+			Expect(controller.SendWindowSize()).To(BeZero())
+			blocked, blockedAt := controller.IsNewlyBlocked()
+			Expect(blocked).To(BeTrue())
+			Expect(blockedAt).To(Equal(protocol.ByteCount(1000)))
+		})
+
 		It("says if is blocked after resetting", func() {
 			const initialWindow protocol.ByteCount = 1337
 			controller.UpdateSendWindow(initialWindow)
@@ -177,6 +193,7 @@ var _ = Describe("Connection Flow controller", func() {
 			blocked, _ := controller.IsNewlyBlocked()
 			Expect(blocked).To(BeTrue())
 			Expect(controller.Reset()).To(Succeed())
+			controller.UpdateSendWindow(initialWindow)
 			controller.AddBytesSent(initialWindow)
 			blocked, blockedAt := controller.IsNewlyBlocked()
 			Expect(blocked).To(BeTrue())
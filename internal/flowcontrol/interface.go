@@ -11,6 +11,8 @@ type flowController interface {
 	AddBytesRead(protocol.ByteCount)
 	GetWindowUpdate() protocol.ByteCount // returns 0 if no update is necessary
 	IsNewlyBlocked() (bool, protocol.ByteCount)
+	// for debugging
+	State() State
 }
 
 // A StreamFlowController is a flow controller for a QUIC stream.
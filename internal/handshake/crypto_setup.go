@@ -63,6 +63,9 @@ type cryptoSetup struct {
 
 	used0RTT atomic.Bool
 
+	keyExchangeGroup atomic.Uint32 // a tls.CurveID
+	signatureScheme  atomic.Uint32 // a tls.SignatureScheme
+
 	aead          *updatableAEAD
 	has1RTTSealer bool
 	has1RTTOpener bool
@@ -252,6 +255,9 @@ func (h *cryptoSetup) handleMessage(data []byte, encLevel protocol.EncryptionLev
 	if err := h.conn.HandleData(qtls.ToTLSEncryptionLevel(encLevel), data); err != nil {
 		return err
 	}
+	if h.perspective == protocol.PerspectiveClient {
+		h.inspectServerMessages(data)
+	}
 	for {
 		ev := h.conn.NextEvent()
 		done, err := h.handleEvent(ev)
@@ -541,6 +547,9 @@ func (h *cryptoSetup) SetWriteKey(el tls.QUICEncryptionLevel, suiteID uint16, tr
 
 // WriteRecord is called when TLS writes data
 func (h *cryptoSetup) WriteRecord(encLevel qtls.QUICEncryptionLevel, p []byte) {
+	if h.perspective == protocol.PerspectiveServer {
+		h.inspectServerMessages(p)
+	}
 	//nolint:exhaustive // handshake records can only be written for Initial and Handshake.
 	switch encLevel {
 	case qtls.QUICEncryptionLevelInitial:
@@ -554,6 +563,23 @@ func (h *cryptoSetup) WriteRecord(encLevel qtls.QUICEncryptionLevel, p []byte) {
 	}
 }
 
+// inspectServerMessages records the key exchange group and the signature scheme
+// from the handshake messages sent by the server.
+func (h *cryptoSetup) inspectServerMessages(data []byte) {
+	forEachHandshakeMessage(data, func(typ uint8, body []byte) {
+		switch typ {
+		case typeServerHello:
+			if group, ok := parseServerHelloKeyShare(body); ok {
+				h.keyExchangeGroup.Store(uint32(group))
+			}
+		case typeCertificateVerify:
+			if scheme, ok := parseCertificateVerifyScheme(body); ok {
+				h.signatureScheme.Store(uint32(scheme))
+			}
+		}
+	})
+}
+
 func (h *cryptoSetup) DiscardInitialKeys() {
 	h.mutex.Lock()
 	dropped := h.initialOpener != nil
@@ -687,8 +713,10 @@ func (h *cryptoSetup) Get1RTTOpener() (ShortHeaderOpener, error) {
 
 func (h *cryptoSetup) ConnectionState() ConnectionState {
 	return ConnectionState{
-		ConnectionState: h.conn.ConnectionState(),
-		Used0RTT:        h.used0RTT.Load(),
+		ConnectionState:  h.conn.ConnectionState(),
+		Used0RTT:         h.used0RTT.Load(),
+		KeyExchangeGroup: tls.CurveID(h.keyExchangeGroup.Load()),
+		SignatureScheme:  tls.SignatureScheme(h.signatureScheme.Load()),
 	}
 }
 
@@ -17,6 +17,7 @@ import (
 	"github.com/quic-go/quic-go/internal/testdata"
 	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
+	"github.com/quic-go/quic-go/quicvarint"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -305,9 +306,26 @@ var _ = Describe("Crypto Setup TLS", func() {
 			Expect(serverErr).ToNot(HaveOccurred())
 		})
 
+		It("reports the key exchange group and the signature scheme", func() {
+			clientConf.CurvePreferences = []tls.CurveID{tls.X25519}
+			client, _, clientErr, server, _, serverErr := handshakeWithTLSConf(
+				clientConf, serverConf,
+				&utils.RTTStats{}, &utils.RTTStats{},
+				&wire.TransportParameters{ActiveConnectionIDLimit: 2}, &wire.TransportParameters{ActiveConnectionIDLimit: 2},
+				false,
+			)
+			Expect(clientErr).ToNot(HaveOccurred())
+			Expect(serverErr).ToNot(HaveOccurred())
+			for _, cs := range []ConnectionState{client.ConnectionState(), server.ConnectionState()} {
+				Expect(cs.KeyExchangeGroup).To(Equal(tls.X25519))
+				// the server's certificate uses an RSA key
+				Expect(cs.SignatureScheme).To(Equal(tls.PSSWithSHA256))
+			}
+		})
+
 		It("performs a HelloRetryRequst", func() {
 			serverConf.CurvePreferences = []tls.CurveID{tls.CurveP384}
-			_, _, clientErr, _, _, serverErr := handshakeWithTLSConf(
+			client, _, clientErr, server, _, serverErr := handshakeWithTLSConf(
 				clientConf, serverConf,
 				&utils.RTTStats{}, &utils.RTTStats{},
 				&wire.TransportParameters{ActiveConnectionIDLimit: 2}, &wire.TransportParameters{ActiveConnectionIDLimit: 2},
@@ -315,6 +333,8 @@ var _ = Describe("Crypto Setup TLS", func() {
 			)
 			Expect(clientErr).ToNot(HaveOccurred())
 			Expect(serverErr).ToNot(HaveOccurred())
+			Expect(client.ConnectionState().KeyExchangeGroup).To(Equal(tls.CurveP384))
+			Expect(server.ConnectionState().KeyExchangeGroup).To(Equal(tls.CurveP384))
 		})
 
 		It("handshakes with client auth", func() {
@@ -384,6 +404,28 @@ var _ = Describe("Crypto Setup TLS", func() {
 			Expect(serverReceivedTransportParameters.MaxIdleTimeout).To(Equal(42 * time.Second))
 		})
 
+		It("rejects a max_udp_payload_size smaller than 1200 bytes", func() {
+			client := NewCryptoSetupClient(
+				protocol.ConnectionID{},
+				&wire.TransportParameters{ActiveConnectionIDLimit: 2},
+				clientConf,
+				false,
+				&utils.RTTStats{},
+				nil,
+				utils.DefaultLogger.WithPrefix("client"),
+				protocol.Version1,
+			)
+			b := quicvarint.Append(nil, 0x3) // max_udp_payload_size
+			b = quicvarint.Append(b, uint64(quicvarint.Len(1199)))
+			b = quicvarint.Append(b, 1199)
+			err := client.(*cryptoSetup).handleTransportParameters(b)
+			Expect(err).To(MatchError(&qerr.TransportError{
+				ErrorCode:    qerr.TransportParameterError,
+				ErrorMessage: "invalid value for max_packet_size: 1199 (minimum 1200)",
+			}))
+			Expect(client.NextEvent().Kind).To(Equal(EventNoEvent))
+		})
+
 		Context("with session tickets", func() {
 			It("errors when the NewSessionTicket is sent at the wrong encryption level", func() {
 				client, _, clientErr, _, _, serverErr := handshakeWithTLSConf(
@@ -460,6 +502,9 @@ var _ = Describe("Crypto Setup TLS", func() {
 				Eventually(receivedSessionTicket).Should(BeClosed())
 				Expect(server.ConnectionState().DidResume).To(BeTrue())
 				Expect(client.ConnectionState().DidResume).To(BeTrue())
+				// no CertificateVerify is sent when resuming a session
+				Expect(server.ConnectionState().SignatureScheme).To(BeZero())
+				Expect(client.ConnectionState().SignatureScheme).To(BeZero())
 				Expect(clientRTTStats.SmoothedRTT()).To(Equal(clientRTT))
 				Expect(serverRTTStats.SmoothedRTT()).To(Equal(serverRTT))
 			})
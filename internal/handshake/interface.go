@@ -55,7 +55,9 @@ type ShortHeaderSealer interface {
 
 type ConnectionState struct {
 	tls.ConnectionState
-	Used0RTT bool
+	Used0RTT         bool
+	KeyExchangeGroup tls.CurveID
+	SignatureScheme  tls.SignatureScheme
 }
 
 // EventKind is the kind of handshake event.
@@ -0,0 +1,71 @@
+package handshake
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+)
+
+// TLS handshake message types, see RFC 8446, section 4.
+const (
+	typeServerHello       = 2
+	typeCertificateVerify = 15
+)
+
+const extensionKeyShare = 51
+
+// forEachHandshakeMessage calls fn for every complete TLS handshake message contained in data.
+func forEachHandshakeMessage(data []byte, fn func(typ uint8, body []byte)) {
+	for len(data) >= 4 {
+		l := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
+		if len(data) < 4+l {
+			return
+		}
+		fn(data[0], data[4:4+l])
+		data = data[4+l:]
+	}
+}
+
+// parseServerHelloKeyShare parses the key exchange group from the key_share extension
+// of a ServerHello (or a HelloRetryRequest) message.
+func parseServerHelloKeyShare(b []byte) (tls.CurveID, bool) {
+	// legacy_version (2 bytes) and random (32 bytes)
+	if len(b) < 34+1 {
+		return 0, false
+	}
+	b = b[34:]
+	// legacy_session_id_echo, cipher_suite (2 bytes), legacy_compression_method (1 byte)
+	sessionIDLen := int(b[0])
+	if len(b) < 1+sessionIDLen+3+2 {
+		return 0, false
+	}
+	b = b[1+sessionIDLen+3:]
+	extensionsLen := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	if len(b) < extensionsLen {
+		return 0, false
+	}
+	b = b[:extensionsLen]
+	for len(b) >= 4 {
+		typ := binary.BigEndian.Uint16(b)
+		l := int(binary.BigEndian.Uint16(b[2:]))
+		b = b[4:]
+		if len(b) < l {
+			return 0, false
+		}
+		// Both the KeyShareEntry of the ServerHello and the selected_group of the HelloRetryRequest
+		// start with the 2 byte group.
+		if typ == extensionKeyShare && l >= 2 {
+			return tls.CurveID(binary.BigEndian.Uint16(b)), true
+		}
+		b = b[l:]
+	}
+	return 0, false
+}
+
+// parseCertificateVerifyScheme parses the signature scheme from a CertificateVerify message.
+func parseCertificateVerifyScheme(b []byte) (tls.SignatureScheme, bool) {
+	if len(b) < 2 {
+		return 0, false
+	}
+	return tls.SignatureScheme(binary.BigEndian.Uint16(b)), true
+}
@@ -0,0 +1,58 @@
+package handshake
+
+import (
+	"crypto/tls"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TLS handshake messages", func() {
+	// a ServerHello with a session ID of 3 bytes, a supported_versions and a key_share extension
+	serverHello := func(group tls.CurveID) []byte {
+		b := []byte{0x3, 0x3}                // legacy_version
+		b = append(b, make([]byte, 32)...)   // random
+		b = append(b, 3, 'f', 'o', 'o')      // legacy_session_id_echo
+		b = append(b, 0x13, 0x1)             // cipher_suite
+		b = append(b, 0)                     // legacy_compression_method
+		b = append(b, 0, 6+8)                // extensions length
+		b = append(b, 0, 43, 0, 2, 0x3, 0x4) // supported_versions
+		b = append(b, 0, 51, 0, 4, byte(group>>8), byte(group), 0, 0)
+		return b
+	}
+
+	It("splits handshake messages", func() {
+		data := []byte{1, 0, 0, 3, 'f', 'o', 'o', 2, 0, 0, 0, 3, 0, 0, 5, 'b', 'a'}
+		var types []uint8
+		var bodies [][]byte
+		forEachHandshakeMessage(data, func(typ uint8, body []byte) {
+			types = append(types, typ)
+			bodies = append(bodies, body)
+		})
+		// the last message is incomplete
+		Expect(types).To(Equal([]uint8{1, 2}))
+		Expect(bodies).To(Equal([][]byte{[]byte("foo"), {}}))
+	})
+
+	It("parses the key exchange group from a ServerHello", func() {
+		group, ok := parseServerHelloKeyShare(serverHello(tls.X25519))
+		Expect(ok).To(BeTrue())
+		Expect(group).To(Equal(tls.X25519))
+	})
+
+	It("handles truncated ServerHello messages", func() {
+		b := serverHello(tls.CurveP256)
+		for i := 0; i < len(b)-4; i++ {
+			_, ok := parseServerHelloKeyShare(b[:i])
+			Expect(ok).To(BeFalse())
+		}
+	})
+
+	It("parses the signature scheme from a CertificateVerify", func() {
+		scheme, ok := parseCertificateVerifyScheme([]byte{0x8, 0x4, 0, 2, 0xde, 0xad})
+		Expect(ok).To(BeTrue())
+		Expect(scheme).To(Equal(tls.PSSWithSHA256))
+		_, ok = parseCertificateVerifyScheme([]byte{0x8})
+		Expect(ok).To(BeFalse())
+	})
+})
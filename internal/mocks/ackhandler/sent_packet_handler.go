@@ -13,6 +13,7 @@ import (
 	time "time"
 
 	ackhandler "github.com/quic-go/quic-go/internal/ackhandler"
+	congestion "github.com/quic-go/quic-go/internal/congestion"
 	protocol "github.com/quic-go/quic-go/internal/protocol"
 	wire "github.com/quic-go/quic-go/internal/wire"
 	gomock "go.uber.org/mock/gomock"
@@ -154,17 +155,17 @@ func (c *SentPacketHandlerGetLossDetectionTimeoutCall) DoAndReturn(f func() time
 }
 
 // OnLossDetectionTimeout mocks base method.
-func (m *MockSentPacketHandler) OnLossDetectionTimeout() error {
+func (m *MockSentPacketHandler) OnLossDetectionTimeout(arg0 time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "OnLossDetectionTimeout")
+	ret := m.ctrl.Call(m, "OnLossDetectionTimeout", arg0)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // OnLossDetectionTimeout indicates an expected call of OnLossDetectionTimeout.
-func (mr *MockSentPacketHandlerMockRecorder) OnLossDetectionTimeout() *SentPacketHandlerOnLossDetectionTimeoutCall {
+func (mr *MockSentPacketHandlerMockRecorder) OnLossDetectionTimeout(arg0 any) *SentPacketHandlerOnLossDetectionTimeoutCall {
 	mr.mock.ctrl.T.Helper()
-	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnLossDetectionTimeout", reflect.TypeOf((*MockSentPacketHandler)(nil).OnLossDetectionTimeout))
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnLossDetectionTimeout", reflect.TypeOf((*MockSentPacketHandler)(nil).OnLossDetectionTimeout), arg0)
 	return &SentPacketHandlerOnLossDetectionTimeoutCall{Call: call}
 }
 
@@ -180,13 +181,13 @@ func (c *SentPacketHandlerOnLossDetectionTimeoutCall) Return(arg0 error) *SentPa
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *SentPacketHandlerOnLossDetectionTimeoutCall) Do(f func() error) *SentPacketHandlerOnLossDetectionTimeoutCall {
+func (c *SentPacketHandlerOnLossDetectionTimeoutCall) Do(f func(time.Time) error) *SentPacketHandlerOnLossDetectionTimeoutCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *SentPacketHandlerOnLossDetectionTimeoutCall) DoAndReturn(f func() error) *SentPacketHandlerOnLossDetectionTimeoutCall {
+func (c *SentPacketHandlerOnLossDetectionTimeoutCall) DoAndReturn(f func(time.Time) error) *SentPacketHandlerOnLossDetectionTimeoutCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
@@ -458,15 +459,15 @@ func (c *SentPacketHandlerSendModeCall) DoAndReturn(f func(time.Time) ackhandler
 }
 
 // SentPacket mocks base method.
-func (m *MockSentPacketHandler) SentPacket(arg0 time.Time, arg1, arg2 protocol.PacketNumber, arg3 []ackhandler.StreamFrame, arg4 []ackhandler.Frame, arg5 protocol.EncryptionLevel, arg6 protocol.ECN, arg7 protocol.ByteCount, arg8 bool) {
+func (m *MockSentPacketHandler) SentPacket(arg0 time.Time, arg1, arg2 protocol.PacketNumber, arg3 []ackhandler.StreamFrame, arg4 []ackhandler.Frame, arg5 protocol.EncryptionLevel, arg6 protocol.ECN, arg7 protocol.ByteCount, arg8, arg9 bool) {
 	m.ctrl.T.Helper()
-	m.ctrl.Call(m, "SentPacket", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+	m.ctrl.Call(m, "SentPacket", arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
 }
 
 // SentPacket indicates an expected call of SentPacket.
-func (mr *MockSentPacketHandlerMockRecorder) SentPacket(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8 any) *SentPacketHandlerSentPacketCall {
+func (mr *MockSentPacketHandlerMockRecorder) SentPacket(arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9 any) *SentPacketHandlerSentPacketCall {
 	mr.mock.ctrl.T.Helper()
-	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SentPacket", reflect.TypeOf((*MockSentPacketHandler)(nil).SentPacket), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8)
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SentPacket", reflect.TypeOf((*MockSentPacketHandler)(nil).SentPacket), arg0, arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9)
 	return &SentPacketHandlerSentPacketCall{Call: call}
 }
 
@@ -482,13 +483,49 @@ func (c *SentPacketHandlerSentPacketCall) Return() *SentPacketHandlerSentPacketC
 }
 
 // Do rewrite *gomock.Call.Do
-func (c *SentPacketHandlerSentPacketCall) Do(f func(time.Time, protocol.PacketNumber, protocol.PacketNumber, []ackhandler.StreamFrame, []ackhandler.Frame, protocol.EncryptionLevel, protocol.ECN, protocol.ByteCount, bool)) *SentPacketHandlerSentPacketCall {
+func (c *SentPacketHandlerSentPacketCall) Do(f func(time.Time, protocol.PacketNumber, protocol.PacketNumber, []ackhandler.StreamFrame, []ackhandler.Frame, protocol.EncryptionLevel, protocol.ECN, protocol.ByteCount, bool, bool)) *SentPacketHandlerSentPacketCall {
 	c.Call = c.Call.Do(f)
 	return c
 }
 
 // DoAndReturn rewrite *gomock.Call.DoAndReturn
-func (c *SentPacketHandlerSentPacketCall) DoAndReturn(f func(time.Time, protocol.PacketNumber, protocol.PacketNumber, []ackhandler.StreamFrame, []ackhandler.Frame, protocol.EncryptionLevel, protocol.ECN, protocol.ByteCount, bool)) *SentPacketHandlerSentPacketCall {
+func (c *SentPacketHandlerSentPacketCall) DoAndReturn(f func(time.Time, protocol.PacketNumber, protocol.PacketNumber, []ackhandler.StreamFrame, []ackhandler.Frame, protocol.EncryptionLevel, protocol.ECN, protocol.ByteCount, bool, bool)) *SentPacketHandlerSentPacketCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetCongestionController mocks base method.
+func (m *MockSentPacketHandler) SetCongestionController(arg0 congestion.SendAlgorithmWithDebugInfos) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetCongestionController", arg0)
+}
+
+// SetCongestionController indicates an expected call of SetCongestionController.
+func (mr *MockSentPacketHandlerMockRecorder) SetCongestionController(arg0 any) *SentPacketHandlerSetCongestionControllerCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetCongestionController", reflect.TypeOf((*MockSentPacketHandler)(nil).SetCongestionController), arg0)
+	return &SentPacketHandlerSetCongestionControllerCall{Call: call}
+}
+
+// SentPacketHandlerSetCongestionControllerCall wrap *gomock.Call
+type SentPacketHandlerSetCongestionControllerCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *SentPacketHandlerSetCongestionControllerCall) Return() *SentPacketHandlerSetCongestionControllerCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *SentPacketHandlerSetCongestionControllerCall) Do(f func(congestion.SendAlgorithmWithDebugInfos)) *SentPacketHandlerSetCongestionControllerCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *SentPacketHandlerSetCongestionControllerCall) DoAndReturn(f func(congestion.SendAlgorithmWithDebugInfos)) *SentPacketHandlerSetCongestionControllerCall {
 	c.Call = c.Call.DoAndReturn(f)
 	return c
 }
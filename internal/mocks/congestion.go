@@ -229,6 +229,42 @@ func (c *SendAlgorithmWithDebugInfosInSlowStartCall) DoAndReturn(f func() bool)
 	return c
 }
 
+// InheritState mocks base method.
+func (m *MockSendAlgorithmWithDebugInfos) InheritState(arg0, arg1, arg2 protocol.ByteCount) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "InheritState", arg0, arg1, arg2)
+}
+
+// InheritState indicates an expected call of InheritState.
+func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) InheritState(arg0, arg1, arg2 any) *SendAlgorithmWithDebugInfosInheritStateCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InheritState", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).InheritState), arg0, arg1, arg2)
+	return &SendAlgorithmWithDebugInfosInheritStateCall{Call: call}
+}
+
+// SendAlgorithmWithDebugInfosInheritStateCall wrap *gomock.Call
+type SendAlgorithmWithDebugInfosInheritStateCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *SendAlgorithmWithDebugInfosInheritStateCall) Return() *SendAlgorithmWithDebugInfosInheritStateCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *SendAlgorithmWithDebugInfosInheritStateCall) Do(f func(protocol.ByteCount, protocol.ByteCount, protocol.ByteCount)) *SendAlgorithmWithDebugInfosInheritStateCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *SendAlgorithmWithDebugInfosInheritStateCall) DoAndReturn(f func(protocol.ByteCount, protocol.ByteCount, protocol.ByteCount)) *SendAlgorithmWithDebugInfosInheritStateCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // MaybeExitSlowStart mocks base method.
 func (m *MockSendAlgorithmWithDebugInfos) MaybeExitSlowStart() {
 	m.ctrl.T.Helper()
@@ -409,6 +445,42 @@ func (c *SendAlgorithmWithDebugInfosOnRetransmissionTimeoutCall) DoAndReturn(f f
 	return c
 }
 
+// OnSpuriousCongestionEvent mocks base method.
+func (m *MockSendAlgorithmWithDebugInfos) OnSpuriousCongestionEvent(arg0 protocol.PacketNumber) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnSpuriousCongestionEvent", arg0)
+}
+
+// OnSpuriousCongestionEvent indicates an expected call of OnSpuriousCongestionEvent.
+func (mr *MockSendAlgorithmWithDebugInfosMockRecorder) OnSpuriousCongestionEvent(arg0 any) *SendAlgorithmWithDebugInfosOnSpuriousCongestionEventCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnSpuriousCongestionEvent", reflect.TypeOf((*MockSendAlgorithmWithDebugInfos)(nil).OnSpuriousCongestionEvent), arg0)
+	return &SendAlgorithmWithDebugInfosOnSpuriousCongestionEventCall{Call: call}
+}
+
+// SendAlgorithmWithDebugInfosOnSpuriousCongestionEventCall wrap *gomock.Call
+type SendAlgorithmWithDebugInfosOnSpuriousCongestionEventCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *SendAlgorithmWithDebugInfosOnSpuriousCongestionEventCall) Return() *SendAlgorithmWithDebugInfosOnSpuriousCongestionEventCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *SendAlgorithmWithDebugInfosOnSpuriousCongestionEventCall) Do(f func(protocol.PacketNumber)) *SendAlgorithmWithDebugInfosOnSpuriousCongestionEventCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *SendAlgorithmWithDebugInfosOnSpuriousCongestionEventCall) DoAndReturn(f func(protocol.PacketNumber)) *SendAlgorithmWithDebugInfosOnSpuriousCongestionEventCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // SetMaxDatagramSize mocks base method.
 func (m *MockSendAlgorithmWithDebugInfos) SetMaxDatagramSize(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -11,6 +11,7 @@ package mocks
 import (
 	reflect "reflect"
 
+	flowcontrol "github.com/quic-go/quic-go/internal/flowcontrol"
 	protocol "github.com/quic-go/quic-go/internal/protocol"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -263,6 +264,44 @@ func (c *ConnectionFlowControllerSendWindowSizeCall) DoAndReturn(f func() protoc
 	return c
 }
 
+// State mocks base method.
+func (m *MockConnectionFlowController) State() flowcontrol.State {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "State")
+	ret0, _ := ret[0].(flowcontrol.State)
+	return ret0
+}
+
+// State indicates an expected call of State.
+func (mr *MockConnectionFlowControllerMockRecorder) State() *ConnectionFlowControllerStateCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*MockConnectionFlowController)(nil).State))
+	return &ConnectionFlowControllerStateCall{Call: call}
+}
+
+// ConnectionFlowControllerStateCall wrap *gomock.Call
+type ConnectionFlowControllerStateCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *ConnectionFlowControllerStateCall) Return(arg0 flowcontrol.State) *ConnectionFlowControllerStateCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *ConnectionFlowControllerStateCall) Do(f func() flowcontrol.State) *ConnectionFlowControllerStateCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *ConnectionFlowControllerStateCall) DoAndReturn(f func() flowcontrol.State) *ConnectionFlowControllerStateCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // UpdateSendWindow mocks base method.
 func (m *MockConnectionFlowController) UpdateSendWindow(arg0 protocol.ByteCount) {
 	m.ctrl.T.Helper()
@@ -12,6 +12,7 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	quic "github.com/quic-go/quic-go"
 	qerr "github.com/quic-go/quic-go/internal/qerr"
@@ -271,6 +272,44 @@ func (c *EarlyConnectionHandshakeCompleteCall) DoAndReturn(f func() <-chan struc
 	return c
 }
 
+// LastActivity mocks base method.
+func (m *MockEarlyConnection) LastActivity() time.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastActivity")
+	ret0, _ := ret[0].(time.Time)
+	return ret0
+}
+
+// LastActivity indicates an expected call of LastActivity.
+func (mr *MockEarlyConnectionMockRecorder) LastActivity() *EarlyConnectionLastActivityCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastActivity", reflect.TypeOf((*MockEarlyConnection)(nil).LastActivity))
+	return &EarlyConnectionLastActivityCall{Call: call}
+}
+
+// EarlyConnectionLastActivityCall wrap *gomock.Call
+type EarlyConnectionLastActivityCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *EarlyConnectionLastActivityCall) Return(arg0 time.Time) *EarlyConnectionLastActivityCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *EarlyConnectionLastActivityCall) Do(f func() time.Time) *EarlyConnectionLastActivityCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *EarlyConnectionLastActivityCall) DoAndReturn(f func() time.Time) *EarlyConnectionLastActivityCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // LocalAddr mocks base method.
 func (m *MockEarlyConnection) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -309,6 +348,44 @@ func (c *EarlyConnectionLocalAddrCall) DoAndReturn(f func() net.Addr) *EarlyConn
 	return c
 }
 
+// MaxPacketSize mocks base method.
+func (m *MockEarlyConnection) MaxPacketSize() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MaxPacketSize")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// MaxPacketSize indicates an expected call of MaxPacketSize.
+func (mr *MockEarlyConnectionMockRecorder) MaxPacketSize() *EarlyConnectionMaxPacketSizeCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxPacketSize", reflect.TypeOf((*MockEarlyConnection)(nil).MaxPacketSize))
+	return &EarlyConnectionMaxPacketSizeCall{Call: call}
+}
+
+// EarlyConnectionMaxPacketSizeCall wrap *gomock.Call
+type EarlyConnectionMaxPacketSizeCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *EarlyConnectionMaxPacketSizeCall) Return(arg0 int) *EarlyConnectionMaxPacketSizeCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *EarlyConnectionMaxPacketSizeCall) Do(f func() int) *EarlyConnectionMaxPacketSizeCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *EarlyConnectionMaxPacketSizeCall) DoAndReturn(f func() int) *EarlyConnectionMaxPacketSizeCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // NextConnection mocks base method.
 func (m *MockEarlyConnection) NextConnection() quic.Connection {
 	m.ctrl.T.Helper()
@@ -503,6 +580,45 @@ func (c *EarlyConnectionOpenUniStreamSyncCall) DoAndReturn(f func(context.Contex
 	return c
 }
 
+// ProbePath mocks base method.
+func (m *MockEarlyConnection) ProbePath(arg0 context.Context, arg1 net.Addr) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProbePath", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProbePath indicates an expected call of ProbePath.
+func (mr *MockEarlyConnectionMockRecorder) ProbePath(arg0, arg1 any) *EarlyConnectionProbePathCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProbePath", reflect.TypeOf((*MockEarlyConnection)(nil).ProbePath), arg0, arg1)
+	return &EarlyConnectionProbePathCall{Call: call}
+}
+
+// EarlyConnectionProbePathCall wrap *gomock.Call
+type EarlyConnectionProbePathCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *EarlyConnectionProbePathCall) Return(arg0 bool, arg1 error) *EarlyConnectionProbePathCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *EarlyConnectionProbePathCall) Do(f func(context.Context, net.Addr) (bool, error)) *EarlyConnectionProbePathCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *EarlyConnectionProbePathCall) DoAndReturn(f func(context.Context, net.Addr) (bool, error)) *EarlyConnectionProbePathCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // ReceiveDatagram mocks base method.
 func (m *MockEarlyConnection) ReceiveDatagram(arg0 context.Context) ([]byte, error) {
 	m.ctrl.T.Helper()
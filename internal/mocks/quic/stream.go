@@ -189,6 +189,44 @@ func (c *StreamContextCall) DoAndReturn(f func() context.Context) *StreamContext
 	return c
 }
 
+// IsLocal mocks base method.
+func (m *MockStream) IsLocal() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsLocal")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsLocal indicates an expected call of IsLocal.
+func (mr *MockStreamMockRecorder) IsLocal() *StreamIsLocalCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsLocal", reflect.TypeOf((*MockStream)(nil).IsLocal))
+	return &StreamIsLocalCall{Call: call}
+}
+
+// StreamIsLocalCall wrap *gomock.Call
+type StreamIsLocalCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *StreamIsLocalCall) Return(arg0 bool) *StreamIsLocalCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *StreamIsLocalCall) Do(f func() bool) *StreamIsLocalCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *StreamIsLocalCall) DoAndReturn(f func() bool) *StreamIsLocalCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // Read mocks base method.
 func (m *MockStream) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -380,6 +418,44 @@ func (c *StreamStreamIDCall) DoAndReturn(f func() protocol.StreamID) *StreamStre
 	return c
 }
 
+// WaitUntilAcked mocks base method.
+func (m *MockStream) WaitUntilAcked(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitUntilAcked", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitUntilAcked indicates an expected call of WaitUntilAcked.
+func (mr *MockStreamMockRecorder) WaitUntilAcked(arg0 any) *StreamWaitUntilAckedCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitUntilAcked", reflect.TypeOf((*MockStream)(nil).WaitUntilAcked), arg0)
+	return &StreamWaitUntilAckedCall{Call: call}
+}
+
+// StreamWaitUntilAckedCall wrap *gomock.Call
+type StreamWaitUntilAckedCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *StreamWaitUntilAckedCall) Return(arg0 error) *StreamWaitUntilAckedCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *StreamWaitUntilAckedCall) Do(f func(context.Context) error) *StreamWaitUntilAckedCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *StreamWaitUntilAckedCall) DoAndReturn(f func(context.Context) error) *StreamWaitUntilAckedCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // Write mocks base method.
 func (m *MockStream) Write(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
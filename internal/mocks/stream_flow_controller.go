@@ -11,6 +11,7 @@ package mocks
 import (
 	reflect "reflect"
 
+	flowcontrol "github.com/quic-go/quic-go/internal/flowcontrol"
 	protocol "github.com/quic-go/quic-go/internal/protocol"
 	gomock "go.uber.org/mock/gomock"
 )
@@ -261,6 +262,44 @@ func (c *StreamFlowControllerSendWindowSizeCall) DoAndReturn(f func() protocol.B
 	return c
 }
 
+// State mocks base method.
+func (m *MockStreamFlowController) State() flowcontrol.State {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "State")
+	ret0, _ := ret[0].(flowcontrol.State)
+	return ret0
+}
+
+// State indicates an expected call of State.
+func (mr *MockStreamFlowControllerMockRecorder) State() *StreamFlowControllerStateCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "State", reflect.TypeOf((*MockStreamFlowController)(nil).State))
+	return &StreamFlowControllerStateCall{Call: call}
+}
+
+// StreamFlowControllerStateCall wrap *gomock.Call
+type StreamFlowControllerStateCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *StreamFlowControllerStateCall) Return(arg0 flowcontrol.State) *StreamFlowControllerStateCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *StreamFlowControllerStateCall) Do(f func() flowcontrol.State) *StreamFlowControllerStateCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *StreamFlowControllerStateCall) DoAndReturn(f func() flowcontrol.State) *StreamFlowControllerStateCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // UpdateHighestReceived mocks base method.
 func (m *MockStreamFlowController) UpdateHighestReceived(arg0 protocol.ByteCount, arg1 bool) error {
 	m.ctrl.T.Helper()
@@ -7,6 +7,9 @@ type PacketNumber int64
 // In QUIC, 0 is a valid packet number.
 const InvalidPacketNumber PacketNumber = -1
 
+// MaxPacketNumber is the largest packet number allowed by RFC 9000 (2^62-1).
+const MaxPacketNumber PacketNumber = 1<<62 - 1
+
 // PacketNumberLen is the length of the packet number in bytes
 type PacketNumberLen uint8
 
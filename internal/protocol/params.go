@@ -45,6 +45,9 @@ const DefaultMaxIncomingStreams = 100
 // DefaultMaxIncomingUniStreams is the maximum number of unidirectional streams that a peer may open
 const DefaultMaxIncomingUniStreams = 100
 
+// DefaultAckElicitingThreshold is the number of ack-eliciting packets received before an ACK is sent
+const DefaultAckElicitingThreshold = 2
+
 // MaxServerUnprocessedPackets is the max number of packets stored in the server that are not yet processed.
 const MaxServerUnprocessedPackets = 1024
 
@@ -152,6 +155,10 @@ const MaxActiveConnectionIDs = 4
 // MaxIssuedConnectionIDs is the maximum number of connection IDs that we're issuing at the same time.
 const MaxIssuedConnectionIDs = 6
 
+// MaxRetiredConnectionIDs is the maximum number of connection IDs that we retired,
+// but for which the RETIRE_CONNECTION_ID frame hasn't been acknowledged yet.
+const MaxRetiredConnectionIDs = 4 * MaxActiveConnectionIDs
+
 // PacketsPerConnectionID is the number of packets we send using one connection ID.
 // If the peer provices us with enough new connection IDs, we switch to a new connection ID.
 const PacketsPerConnectionID = 10000
@@ -113,6 +113,10 @@ const MaxPacketBufferSize = 1452
 // MaxLargePacketBufferSize is used when using GSO
 const MaxLargePacketBufferSize = 20 * 1024
 
+// MaxGSOSegments is the maximum number of packets sent in a single GSO batch.
+// This is the limit imposed by the Linux kernel (UDP_MAX_SEGMENTS).
+const MaxGSOSegments = 64
+
 // MinInitialPacketSize is the minimum size an Initial packet is required to have.
 const MinInitialPacketSize = 1200
 
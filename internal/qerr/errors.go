@@ -8,8 +8,10 @@ import (
 )
 
 var (
-	ErrHandshakeTimeout = &HandshakeTimeoutError{}
-	ErrIdleTimeout      = &IdleTimeoutError{}
+	ErrHandshakeTimeout      = &HandshakeTimeoutError{}
+	ErrHandshakeNoProgress   = &HandshakeNoProgressError{}
+	ErrIdleTimeout           = &IdleTimeoutError{}
+	ErrMaxConnectionDuration = &MaxConnectionDurationError{}
 )
 
 type TransportError struct {
@@ -99,6 +101,24 @@ func (e *HandshakeTimeoutError) Temporary() bool      { return false }
 func (e *HandshakeTimeoutError) Error() string        { return "timeout: handshake did not complete in time" }
 func (e *HandshakeTimeoutError) Is(target error) bool { return target == net.ErrClosed }
 
+// A HandshakeNoProgressError occurs when the peer keeps sending packets, but none of them advance the handshake.
+type HandshakeNoProgressError struct{}
+
+var _ error = &HandshakeNoProgressError{}
+
+func (e *HandshakeNoProgressError) Timeout() bool        { return true }
+func (e *HandshakeNoProgressError) Temporary() bool      { return false }
+func (e *HandshakeNoProgressError) Error() string        { return "timeout: handshake made no progress" }
+func (e *HandshakeNoProgressError) Is(target error) bool { return target == net.ErrClosed }
+
+// A MaxConnectionDurationError occurs when a connection is closed because it reached its maximum lifetime.
+type MaxConnectionDurationError struct{}
+
+var _ error = &MaxConnectionDurationError{}
+
+func (e *MaxConnectionDurationError) Error() string        { return "maximum connection duration reached" }
+func (e *MaxConnectionDurationError) Is(target error) bool { return target == net.ErrClosed }
+
 // A VersionNegotiationError occurs when the client and the server can't agree on a QUIC version.
 type VersionNegotiationError struct {
 	Ours   []protocol.VersionNumber
@@ -94,6 +94,16 @@ var _ = Describe("QUIC Errors", func() {
 			Expect(err.Error()).To(Equal("timeout: handshake did not complete in time"))
 		})
 
+		It("handshake no progress timeouts", func() {
+			//nolint:gosimple // we need to assign to an interface here
+			var err error
+			err = &HandshakeNoProgressError{}
+			nerr, ok := err.(net.Error)
+			Expect(ok).To(BeTrue())
+			Expect(nerr.Timeout()).To(BeTrue())
+			Expect(err.Error()).To(Equal("timeout: handshake made no progress"))
+		})
+
 		It("idle timeouts", func() {
 			//nolint:gosimple // we need to assign to an interface here
 			var err error
@@ -105,6 +115,20 @@ var _ = Describe("QUIC Errors", func() {
 		})
 	})
 
+	Context("maximum connection duration errors", func() {
+		It("has a string representation", func() {
+			Expect((&MaxConnectionDurationError{}).Error()).To(Equal("maximum connection duration reached"))
+		})
+
+		It("is not a timeout error", func() {
+			//nolint:gosimple // we need to assign to an interface here
+			var err error
+			err = &MaxConnectionDurationError{}
+			_, ok := err.(net.Error)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
 	Context("Version Negotiation errors", func() {
 		It("has a string representation", func() {
 			Expect((&VersionNegotiationError{
@@ -136,6 +160,8 @@ var _ = Describe("QUIC Errors", func() {
 		Expect(errors.Is(&ApplicationError{}, net.ErrClosed)).To(BeTrue())
 		Expect(errors.Is(&IdleTimeoutError{}, net.ErrClosed)).To(BeTrue())
 		Expect(errors.Is(&HandshakeTimeoutError{}, net.ErrClosed)).To(BeTrue())
+		Expect(errors.Is(&HandshakeNoProgressError{}, net.ErrClosed)).To(BeTrue())
+		Expect(errors.Is(&MaxConnectionDurationError{}, net.ErrClosed)).To(BeTrue())
 		Expect(errors.Is(&StatelessResetError{}, net.ErrClosed)).To(BeTrue())
 		Expect(errors.Is(&VersionNegotiationError{}, net.ErrClosed)).To(BeTrue())
 	})
@@ -19,6 +19,11 @@ type AckFrame struct {
 	DelayTime time.Duration
 
 	ECT0, ECT1, ECNCE uint64
+
+	// MaxNumRanges is the maximum number of ACK ranges that are serialized.
+	// The ranges for the lowest packet numbers are dropped first.
+	// If 0, the number of ACK ranges is only limited by the maximum ACK frame size.
+	MaxNumRanges int
 }
 
 // parseAckFrame reads an ACK frame
@@ -66,7 +71,9 @@ func parseAckFrame(frame *AckFrame, r *bytes.Reader, typ uint64, ackDelayExponen
 			return err
 		}
 		gap := protocol.PacketNumber(g)
-		if smallest < gap+2 {
+		// Check that smallest - gap - 2 doesn't underflow.
+		// This comparison is written such that it can't overflow for large values of gap.
+		if smallest < 2 || gap > smallest-2 {
 			return errInvalidAckRanges
 		}
 		largest := smallest - gap - 2
@@ -120,7 +127,7 @@ func (f *AckFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
 	b = quicvarint.Append(b, uint64(f.LargestAcked()))
 	b = quicvarint.Append(b, encodeAckDelay(f.DelayTime))
 
-	numRanges := f.numEncodableAckRanges()
+	numRanges := f.numEncodableAckRanges(protocol.MaxAckFrameSize)
 	b = quicvarint.Append(b, uint64(numRanges-1))
 
 	// write the first range
@@ -145,7 +152,7 @@ func (f *AckFrame) Append(b []byte, _ protocol.VersionNumber) ([]byte, error) {
 // Length of a written frame
 func (f *AckFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
 	largestAcked := f.AckRanges[0].Largest
-	numRanges := f.numEncodableAckRanges()
+	numRanges := f.numEncodableAckRanges(protocol.MaxAckFrameSize)
 
 	length := 1 + quicvarint.Len(uint64(largestAcked)) + quicvarint.Len(encodeAckDelay(f.DelayTime))
 
@@ -166,22 +173,37 @@ func (f *AckFrame) Length(_ protocol.VersionNumber) protocol.ByteCount {
 	return length
 }
 
+// EncodedAckRanges returns the ACK ranges that are serialized by Append.
+// Ranges that exceed MaxNumRanges or the maximum ACK frame size are omitted.
+func (f *AckFrame) EncodedAckRanges() []AckRange {
+	return f.AckRanges[:f.numEncodableAckRanges(protocol.MaxAckFrameSize)]
+}
+
 // gets the number of ACK ranges that can be encoded
-// such that the resulting frame is smaller than the maximum ACK frame size
-func (f *AckFrame) numEncodableAckRanges() int {
+// such that the resulting frame is not larger than maxSize,
+// and doesn't contain more than MaxNumRanges ranges.
+// The first ACK range is always encoded, since an ACK frame needs at least one ACK range.
+// An ACK frame with a single ACK range is at most 50 bytes large, so it always fits into protocol.MaxAckFrameSize.
+func (f *AckFrame) numEncodableAckRanges(maxSize protocol.ByteCount) int {
 	length := 1 + quicvarint.Len(uint64(f.LargestAcked())) + quicvarint.Len(encodeAckDelay(f.DelayTime))
 	length += 2 // assume that the number of ranges will consume 2 bytes
-	for i := 1; i < len(f.AckRanges); i++ {
+	_, firstRange := f.encodeAckRange(0)
+	length += quicvarint.Len(firstRange)
+	numRanges := len(f.AckRanges)
+	if f.MaxNumRanges > 0 {
+		numRanges = min(numRanges, f.MaxNumRanges)
+	}
+	for i := 1; i < numRanges; i++ {
 		gap, len := f.encodeAckRange(i)
 		rangeLen := quicvarint.Len(gap) + quicvarint.Len(len)
-		if length+rangeLen > protocol.MaxAckFrameSize {
-			// Writing range i would exceed the MaxAckFrameSize.
-			// So encode one range less than that.
-			return i - 1
+		if length+rangeLen > maxSize {
+			// Writing range i would exceed maxSize.
+			// So only encode the ranges before that.
+			return i
 		}
 		length += rangeLen
 	}
-	return len(f.AckRanges)
+	return numRanges
 }
 
 func (f *AckFrame) encodeAckRange(i int) (uint64 /* gap */, uint64 /* length */) {
@@ -254,6 +276,7 @@ func (f *AckFrame) Reset() {
 	f.ECT0 = 0
 	f.ECT1 = 0
 	f.ECNCE = 0
+	f.MaxNumRanges = 0
 	for _, r := range f.AckRanges {
 		r.Largest = 0
 		r.Smallest = 0
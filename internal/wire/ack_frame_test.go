@@ -87,6 +87,28 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 			Expect(b.Len()).To(BeZero())
 		})
 
+		It("rejects an ACK frame with a gap that would underflow the packet number", func() {
+			data := encodeVarInt(1000)                           // largest acked
+			data = append(data, encodeVarInt(0)...)              // delay
+			data = append(data, encodeVarInt(1)...)              // num blocks
+			data = append(data, encodeVarInt(100)...)            // first ack block
+			data = append(data, encodeVarInt(quicvarint.Max)...) // gap
+			data = append(data, encodeVarInt(0)...)              // ack block
+			var frame AckFrame
+			Expect(parseAckFrame(&frame, bytes.NewReader(data), ackFrameType, protocol.AckDelayExponent, protocol.Version1)).To(MatchError(errInvalidAckRanges))
+		})
+
+		It("rejects an ACK frame with a gap that ends just below packet number 0", func() {
+			data := encodeVarInt(10)                // largest acked
+			data = append(data, encodeVarInt(0)...) // delay
+			data = append(data, encodeVarInt(1)...) // num blocks
+			data = append(data, encodeVarInt(9)...) // first ack block, smallest acked: 1
+			data = append(data, encodeVarInt(0)...) // gap: next largest would be -1
+			data = append(data, encodeVarInt(0)...) // ack block
+			var frame AckFrame
+			Expect(parseAckFrame(&frame, bytes.NewReader(data), ackFrameType, protocol.AckDelayExponent, protocol.Version1)).To(MatchError(errInvalidAckRanges))
+		})
+
 		It("parses an ACK frame that has a multiple blocks", func() {
 			data := encodeVarInt(100)               // largest acked
 			data = append(data, encodeVarInt(0)...) // delay
@@ -339,6 +361,70 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 			Expect(r.Len()).To(BeZero())
 			Expect(len(frame.AckRanges)).To(BeNumerically("<", numRanges)) // make sure we dropped some ranges
 		})
+
+		It("limits the number of ACK ranges, keeping the ranges for the highest packet numbers", func() {
+			f := &AckFrame{
+				AckRanges: []AckRange{
+					{Smallest: 18, Largest: 18},
+					{Smallest: 16, Largest: 16},
+					{Smallest: 14, Largest: 14},
+					{Smallest: 12, Largest: 12},
+					{Smallest: 10, Largest: 10},
+				},
+				MaxNumRanges: 3,
+			}
+			Expect(f.validateAckRanges()).To(BeTrue())
+			b, err := f.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(HaveLen(int(f.Length(protocol.Version1))))
+			r := bytes.NewReader(b)
+			typ, err := quicvarint.Read(r)
+			Expect(err).ToNot(HaveOccurred())
+			var frame AckFrame
+			Expect(parseAckFrame(&frame, r, typ, protocol.AckDelayExponent, protocol.Version1)).To(Succeed())
+			Expect(frame.AckRanges).To(Equal(f.AckRanges[:3]))
+			Expect(frame.AckRanges).To(Equal(f.EncodedAckRanges()))
+			Expect(r.Len()).To(BeZero())
+		})
+
+		It("doesn't limit the number of ACK ranges if the limit is larger than the number of ranges", func() {
+			f := &AckFrame{
+				AckRanges:    []AckRange{{Smallest: 30, Largest: 40}, {Smallest: 10, Largest: 20}},
+				MaxNumRanges: 3,
+			}
+			Expect(f.numEncodableAckRanges(protocol.MaxAckFrameSize)).To(Equal(2))
+		})
+
+		It("always encodes the first ACK range", func() {
+			f := &AckFrame{AckRanges: []AckRange{{Smallest: 30, Largest: 40}, {Smallest: 10, Largest: 20}}}
+			// type (1 byte), largest acked (1 byte), ACK delay (1 byte), number of ranges (assumed to be 2 bytes), first range (1 byte)
+			const minSize = 1 + 1 + 1 + 2 + 1
+			// the second range consumes 2 bytes (gap and length)
+			Expect(f.numEncodableAckRanges(minSize - 1)).To(Equal(1))
+			Expect(f.numEncodableAckRanges(minSize)).To(Equal(1))
+			Expect(f.numEncodableAckRanges(minSize + 1)).To(Equal(1))
+			Expect(f.numEncodableAckRanges(minSize + 2)).To(Equal(2))
+		})
+
+		It("fits the largest possible first ACK range into the maximum ACK frame size", func() {
+			f := &AckFrame{
+				AckRanges: []AckRange{{Smallest: 0, Largest: protocol.MaxPacketNumber}},
+				DelayTime: math.MaxInt64,
+				ECT0:      quicvarint.Max,
+				ECT1:      quicvarint.Max,
+				ECNCE:     quicvarint.Max,
+			}
+			b, err := f.Append(nil, protocol.Version1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(b).To(HaveLen(int(f.Length(protocol.Version1))))
+			Expect(len(b)).To(BeNumerically("<=", protocol.MaxAckFrameSize))
+			r := bytes.NewReader(b)
+			typ, err := quicvarint.Read(r)
+			Expect(err).ToNot(HaveOccurred())
+			var frame AckFrame
+			Expect(parseAckFrame(&frame, r, typ, protocol.AckDelayExponent, protocol.Version1)).To(Succeed())
+			Expect(frame.AckRanges).To(Equal(f.AckRanges))
+		})
 	})
 
 	Context("ACK range validator", func() {
@@ -468,6 +554,8 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 			ECT0:      1,
 			ECT1:      2,
 			ECNCE:     3,
+
+			MaxNumRanges: 10,
 		}
 		f.Reset()
 		Expect(f.AckRanges).To(BeEmpty())
@@ -476,5 +564,6 @@ var _ = Describe("ACK Frame (for IETF QUIC)", func() {
 		Expect(f.ECT0).To(BeZero())
 		Expect(f.ECT1).To(BeZero())
 		Expect(f.ECNCE).To(BeZero())
+		Expect(f.MaxNumRanges).To(BeZero())
 	})
 })
@@ -90,6 +90,15 @@ func (p *frameParser) parseNext(r *bytes.Reader, encLevel protocol.EncryptionLev
 				ErrorMessage: err.Error(),
 			}
 		}
+		// RFC 9000, section 12.4: An endpoint MUST treat receipt of a frame in a packet type
+		// that is not permitted as a connection error of type PROTOCOL_VIOLATION.
+		if !p.isAllowedAtEncLevel(f, encLevel) {
+			return nil, &qerr.TransportError{
+				FrameType:    typ,
+				ErrorCode:    qerr.ProtocolViolation,
+				ErrorMessage: fmt.Sprintf("%s not allowed at encryption level %s", reflect.TypeOf(f).Elem().Name(), encLevel),
+			}
+		}
 		return f, nil
 	}
 	return nil, nil
@@ -157,9 +166,6 @@ func (p *frameParser) parseFrame(r *bytes.Reader, typ uint64, encLevel protocol.
 	if err != nil {
 		return nil, err
 	}
-	if !p.isAllowedAtEncLevel(frame, encLevel) {
-		return nil, fmt.Errorf("%s not allowed at encryption level %s", reflect.TypeOf(frame).Elem().Name(), encLevel)
-	}
 	return frame, nil
 }
 
@@ -174,7 +180,7 @@ func (p *frameParser) isAllowedAtEncLevel(f Frame, encLevel protocol.EncryptionL
 		}
 	case protocol.Encryption0RTT:
 		switch f.(type) {
-		case *CryptoFrame, *AckFrame, *ConnectionCloseFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame:
+		case *CryptoFrame, *AckFrame, *ConnectionCloseFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame, *HandshakeDoneFrame:
 			return false
 		default:
 			return true
@@ -348,6 +348,22 @@ var _ = Describe("Frame parsing", func() {
 		Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FrameEncodingError))
 	})
 
+	It("reports the frame type of invalid STREAM frames", func() {
+		f := &StreamFrame{
+			StreamID:       0x1337,
+			Offset:         0x100,
+			Data:           []byte("foobar"),
+			DataLenPresent: true,
+		}
+		b, err := f.Append(nil, protocol.Version1)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b[0]).To(BeEquivalentTo(0x8 | 0x4 | 0x2)) // STREAM frame with the OFF and the LEN bit set
+		_, _, err = parser.ParseNext(b[:len(b)-2], protocol.Encryption1RTT, protocol.Version1)
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FrameEncodingError))
+		Expect(err.(*qerr.TransportError).FrameType).To(BeEquivalentTo(0x8 | 0x4 | 0x2))
+	})
+
 	Context("encryption level check", func() {
 		frames := []Frame{
 			&PingFrame{},
@@ -391,7 +407,7 @@ var _ = Describe("Frame parsing", func() {
 					Expect(err).ToNot(HaveOccurred())
 				default:
 					Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
-					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FrameEncodingError))
+					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
 					Expect(err.(*qerr.TransportError).ErrorMessage).To(ContainSubstring("not allowed at encryption level Initial"))
 				}
 			}
@@ -405,19 +421,19 @@ var _ = Describe("Frame parsing", func() {
 					Expect(err).ToNot(HaveOccurred())
 				default:
 					Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
-					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FrameEncodingError))
+					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
 					Expect(err.(*qerr.TransportError).ErrorMessage).To(ContainSubstring("not allowed at encryption level Handshake"))
 				}
 			}
 		})
 
-		It("rejects all frames but ACK, CRYPTO, CONNECTION_CLOSE, NEW_TOKEN, PATH_RESPONSE and RETIRE_CONNECTION_ID in 0-RTT packets", func() {
+		It("rejects ACK, CRYPTO, CONNECTION_CLOSE, NEW_TOKEN, PATH_RESPONSE, RETIRE_CONNECTION_ID and HANDSHAKE_DONE frames in 0-RTT packets", func() {
 			for i, b := range framesSerialized {
 				_, _, err := parser.ParseNext(b, protocol.Encryption0RTT, protocol.Version1)
 				switch frames[i].(type) {
-				case *AckFrame, *ConnectionCloseFrame, *CryptoFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame:
+				case *AckFrame, *ConnectionCloseFrame, *CryptoFrame, *NewTokenFrame, *PathResponseFrame, *RetireConnectionIDFrame, *HandshakeDoneFrame:
 					Expect(err).To(BeAssignableToTypeOf(&qerr.TransportError{}))
-					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FrameEncodingError))
+					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.ProtocolViolation))
 					Expect(err.(*qerr.TransportError).ErrorMessage).To(ContainSubstring("not allowed at encryption level 0-RTT"))
 				default:
 					Expect(err).ToNot(HaveOccurred())
@@ -35,6 +35,12 @@ type ConnectionTracer struct {
 	LossTimerCanceled                func()
 	ECNStateUpdated                  func(state ECNState, trigger ECNStateTrigger)
 	ChoseALPN                        func(protocol string)
+	// SentRawFrame and ReceivedRawFrame are called for every frame, together with the serialized frame.
+	// The byte slice must not be retained after the callback returns.
+	// For sent ACK frames, the frame only contains the ACK ranges that were actually serialized.
+	// Recording the serialized frames requires an additional copy, so these should only be used for debugging and interop testing.
+	SentRawFrame     func(EncryptionLevel, Frame, []byte)
+	ReceivedRawFrame func(EncryptionLevel, Frame, []byte)
 	// Close is called when the connection is closed.
 	Close func()
 	Debug func(name, msg string)
@@ -245,6 +251,20 @@ func NewMultiplexedConnectionTracer(tracers ...*ConnectionTracer) *ConnectionTra
 				}
 			}
 		},
+		SentRawFrame: func(encLevel EncryptionLevel, f Frame, b []byte) {
+			for _, t := range tracers {
+				if t.SentRawFrame != nil {
+					t.SentRawFrame(encLevel, f, b)
+				}
+			}
+		},
+		ReceivedRawFrame: func(encLevel EncryptionLevel, f Frame, b []byte) {
+			for _, t := range tracers {
+				if t.ReceivedRawFrame != nil {
+					t.ReceivedRawFrame(encLevel, f, b)
+				}
+			}
+		},
 		Close: func() {
 			for _, t := range tracers {
 				if t.Close != nil {
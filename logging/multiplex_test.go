@@ -269,5 +269,26 @@ var _ = Describe("Tracing", func() {
 			tr2.EXPECT().Close()
 			tracer.Close()
 		})
+
+		It("traces the SentRawFrame and ReceivedRawFrame events", func() {
+			var sent, received []string
+			t1 := &ConnectionTracer{
+				SentRawFrame:     func(EncryptionLevel, Frame, []byte) { sent = append(sent, "t1") },
+				ReceivedRawFrame: func(EncryptionLevel, Frame, []byte) { received = append(received, "t1") },
+			}
+			t2 := &ConnectionTracer{
+				SentRawFrame: func(encLevel EncryptionLevel, f Frame, b []byte) {
+					Expect(encLevel).To(Equal(Encryption1RTT))
+					Expect(f).To(Equal(&PingFrame{}))
+					Expect(b).To(Equal([]byte{0x1}))
+					sent = append(sent, "t2")
+				},
+			}
+			tracer := NewMultiplexedConnectionTracer(t1, t2)
+			tracer.SentRawFrame(Encryption1RTT, &PingFrame{}, []byte{0x1})
+			tracer.ReceivedRawFrame(Encryption1RTT, &PingFrame{}, []byte{0x1})
+			Expect(sent).To(Equal([]string{"t1", "t2"}))
+			Expect(received).To(Equal([]string{"t1"}))
+		})
 	})
 })
@@ -315,6 +315,82 @@ func (c *PackerPackMTUProbePacketCall) DoAndReturn(f func(ackhandler.Frame, prot
 	return c
 }
 
+// PackPathProbePacket mocks base method.
+func (m *MockPacker) PackPathProbePacket(arg0 protocol.ConnectionID, arg1 ackhandler.Frame, arg2 protocol.VersionNumber) (shortHeaderPacket, *packetBuffer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PackPathProbePacket", arg0, arg1, arg2)
+	ret0, _ := ret[0].(shortHeaderPacket)
+	ret1, _ := ret[1].(*packetBuffer)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// PackPathProbePacket indicates an expected call of PackPathProbePacket.
+func (mr *MockPackerMockRecorder) PackPathProbePacket(arg0, arg1, arg2 any) *PackerPackPathProbePacketCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PackPathProbePacket", reflect.TypeOf((*MockPacker)(nil).PackPathProbePacket), arg0, arg1, arg2)
+	return &PackerPackPathProbePacketCall{Call: call}
+}
+
+// PackerPackPathProbePacketCall wrap *gomock.Call
+type PackerPackPathProbePacketCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *PackerPackPathProbePacketCall) Return(arg0 shortHeaderPacket, arg1 *packetBuffer, arg2 error) *PackerPackPathProbePacketCall {
+	c.Call = c.Call.Return(arg0, arg1, arg2)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *PackerPackPathProbePacketCall) Do(f func(protocol.ConnectionID, ackhandler.Frame, protocol.VersionNumber) (shortHeaderPacket, *packetBuffer, error)) *PackerPackPathProbePacketCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *PackerPackPathProbePacketCall) DoAndReturn(f func(protocol.ConnectionID, ackhandler.Frame, protocol.VersionNumber) (shortHeaderPacket, *packetBuffer, error)) *PackerPackPathProbePacketCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
+// SetSpinBit mocks base method.
+func (m *MockPacker) SetSpinBit(arg0 bool) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetSpinBit", arg0)
+}
+
+// SetSpinBit indicates an expected call of SetSpinBit.
+func (mr *MockPackerMockRecorder) SetSpinBit(arg0 any) *PackerSetSpinBitCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSpinBit", reflect.TypeOf((*MockPacker)(nil).SetSpinBit), arg0)
+	return &PackerSetSpinBitCall{Call: call}
+}
+
+// PackerSetSpinBitCall wrap *gomock.Call
+type PackerSetSpinBitCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *PackerSetSpinBitCall) Return() *PackerSetSpinBitCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *PackerSetSpinBitCall) Do(f func(bool)) *PackerSetSpinBitCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *PackerSetSpinBitCall) DoAndReturn(f func(bool)) *PackerSetSpinBitCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // SetToken mocks base method.
 func (m *MockPacker) SetToken(arg0 []byte) {
 	m.ctrl.T.Helper()
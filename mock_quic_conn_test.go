@@ -12,6 +12,7 @@ import (
 	context "context"
 	net "net"
 	reflect "reflect"
+	time "time"
 
 	protocol "github.com/quic-go/quic-go/internal/protocol"
 	qerr "github.com/quic-go/quic-go/internal/qerr"
@@ -309,6 +310,44 @@ func (c *QUICConnHandshakeCompleteCall) DoAndReturn(f func() <-chan struct{}) *Q
 	return c
 }
 
+// LastActivity mocks base method.
+func (m *MockQUICConn) LastActivity() time.Time {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LastActivity")
+	ret0, _ := ret[0].(time.Time)
+	return ret0
+}
+
+// LastActivity indicates an expected call of LastActivity.
+func (mr *MockQUICConnMockRecorder) LastActivity() *QUICConnLastActivityCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastActivity", reflect.TypeOf((*MockQUICConn)(nil).LastActivity))
+	return &QUICConnLastActivityCall{Call: call}
+}
+
+// QUICConnLastActivityCall wrap *gomock.Call
+type QUICConnLastActivityCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *QUICConnLastActivityCall) Return(arg0 time.Time) *QUICConnLastActivityCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *QUICConnLastActivityCall) Do(f func() time.Time) *QUICConnLastActivityCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *QUICConnLastActivityCall) DoAndReturn(f func() time.Time) *QUICConnLastActivityCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // LocalAddr mocks base method.
 func (m *MockQUICConn) LocalAddr() net.Addr {
 	m.ctrl.T.Helper()
@@ -347,6 +386,44 @@ func (c *QUICConnLocalAddrCall) DoAndReturn(f func() net.Addr) *QUICConnLocalAdd
 	return c
 }
 
+// MaxPacketSize mocks base method.
+func (m *MockQUICConn) MaxPacketSize() int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MaxPacketSize")
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// MaxPacketSize indicates an expected call of MaxPacketSize.
+func (mr *MockQUICConnMockRecorder) MaxPacketSize() *QUICConnMaxPacketSizeCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxPacketSize", reflect.TypeOf((*MockQUICConn)(nil).MaxPacketSize))
+	return &QUICConnMaxPacketSizeCall{Call: call}
+}
+
+// QUICConnMaxPacketSizeCall wrap *gomock.Call
+type QUICConnMaxPacketSizeCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *QUICConnMaxPacketSizeCall) Return(arg0 int) *QUICConnMaxPacketSizeCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *QUICConnMaxPacketSizeCall) Do(f func() int) *QUICConnMaxPacketSizeCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *QUICConnMaxPacketSizeCall) DoAndReturn(f func() int) *QUICConnMaxPacketSizeCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // NextConnection mocks base method.
 func (m *MockQUICConn) NextConnection() Connection {
 	m.ctrl.T.Helper()
@@ -541,6 +618,45 @@ func (c *QUICConnOpenUniStreamSyncCall) DoAndReturn(f func(context.Context) (Sen
 	return c
 }
 
+// ProbePath mocks base method.
+func (m *MockQUICConn) ProbePath(arg0 context.Context, arg1 net.Addr) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ProbePath", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProbePath indicates an expected call of ProbePath.
+func (mr *MockQUICConnMockRecorder) ProbePath(arg0, arg1 any) *QUICConnProbePathCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProbePath", reflect.TypeOf((*MockQUICConn)(nil).ProbePath), arg0, arg1)
+	return &QUICConnProbePathCall{Call: call}
+}
+
+// QUICConnProbePathCall wrap *gomock.Call
+type QUICConnProbePathCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *QUICConnProbePathCall) Return(arg0 bool, arg1 error) *QUICConnProbePathCall {
+	c.Call = c.Call.Return(arg0, arg1)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *QUICConnProbePathCall) Do(f func(context.Context, net.Addr) (bool, error)) *QUICConnProbePathCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *QUICConnProbePathCall) DoAndReturn(f func(context.Context, net.Addr) (bool, error)) *QUICConnProbePathCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // ReceiveDatagram mocks base method.
 func (m *MockQUICConn) ReceiveDatagram(arg0 context.Context) ([]byte, error) {
 	m.ctrl.T.Helper()
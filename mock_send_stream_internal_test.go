@@ -231,6 +231,44 @@ func (c *SendStreamIStreamIDCall) DoAndReturn(f func() protocol.StreamID) *SendS
 	return c
 }
 
+// WaitUntilAcked mocks base method.
+func (m *MockSendStreamI) WaitUntilAcked(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitUntilAcked", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitUntilAcked indicates an expected call of WaitUntilAcked.
+func (mr *MockSendStreamIMockRecorder) WaitUntilAcked(arg0 any) *SendStreamIWaitUntilAckedCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitUntilAcked", reflect.TypeOf((*MockSendStreamI)(nil).WaitUntilAcked), arg0)
+	return &SendStreamIWaitUntilAckedCall{Call: call}
+}
+
+// SendStreamIWaitUntilAckedCall wrap *gomock.Call
+type SendStreamIWaitUntilAckedCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *SendStreamIWaitUntilAckedCall) Return(arg0 error) *SendStreamIWaitUntilAckedCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *SendStreamIWaitUntilAckedCall) Do(f func(context.Context) error) *SendStreamIWaitUntilAckedCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *SendStreamIWaitUntilAckedCall) DoAndReturn(f func(context.Context) error) *SendStreamIWaitUntilAckedCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // Write mocks base method.
 func (m *MockSendStreamI) Write(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -380,6 +418,46 @@ func (c *SendStreamIhasDataCall) DoAndReturn(f func() bool) *SendStreamIhasDataC
 	return c
 }
 
+// popRetransmission mocks base method.
+func (m *MockSendStreamI) popRetransmission(arg0 protocol.ByteCount, arg1 protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "popRetransmission", arg0, arg1)
+	ret0, _ := ret[0].(ackhandler.StreamFrame)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(bool)
+	return ret0, ret1, ret2
+}
+
+// popRetransmission indicates an expected call of popRetransmission.
+func (mr *MockSendStreamIMockRecorder) popRetransmission(arg0, arg1 any) *SendStreamIpopRetransmissionCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popRetransmission", reflect.TypeOf((*MockSendStreamI)(nil).popRetransmission), arg0, arg1)
+	return &SendStreamIpopRetransmissionCall{Call: call}
+}
+
+// SendStreamIpopRetransmissionCall wrap *gomock.Call
+type SendStreamIpopRetransmissionCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *SendStreamIpopRetransmissionCall) Return(arg0 ackhandler.StreamFrame, arg1, arg2 bool) *SendStreamIpopRetransmissionCall {
+	c.Call = c.Call.Return(arg0, arg1, arg2)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *SendStreamIpopRetransmissionCall) Do(f func(protocol.ByteCount, protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool)) *SendStreamIpopRetransmissionCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *SendStreamIpopRetransmissionCall) DoAndReturn(f func(protocol.ByteCount, protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool)) *SendStreamIpopRetransmissionCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // popStreamFrame mocks base method.
 func (m *MockSendStreamI) popStreamFrame(arg0 protocol.ByteCount, arg1 protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool) {
 	m.ctrl.T.Helper()
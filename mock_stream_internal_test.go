@@ -191,6 +191,44 @@ func (c *StreamIContextCall) DoAndReturn(f func() context.Context) *StreamIConte
 	return c
 }
 
+// IsLocal mocks base method.
+func (m *MockStreamI) IsLocal() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsLocal")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsLocal indicates an expected call of IsLocal.
+func (mr *MockStreamIMockRecorder) IsLocal() *StreamIIsLocalCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsLocal", reflect.TypeOf((*MockStreamI)(nil).IsLocal))
+	return &StreamIIsLocalCall{Call: call}
+}
+
+// StreamIIsLocalCall wrap *gomock.Call
+type StreamIIsLocalCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *StreamIIsLocalCall) Return(arg0 bool) *StreamIIsLocalCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *StreamIIsLocalCall) Do(f func() bool) *StreamIIsLocalCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *StreamIIsLocalCall) DoAndReturn(f func() bool) *StreamIIsLocalCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // Read mocks base method.
 func (m *MockStreamI) Read(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -382,6 +420,44 @@ func (c *StreamIStreamIDCall) DoAndReturn(f func() protocol.StreamID) *StreamISt
 	return c
 }
 
+// WaitUntilAcked mocks base method.
+func (m *MockStreamI) WaitUntilAcked(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WaitUntilAcked", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WaitUntilAcked indicates an expected call of WaitUntilAcked.
+func (mr *MockStreamIMockRecorder) WaitUntilAcked(arg0 any) *StreamIWaitUntilAckedCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WaitUntilAcked", reflect.TypeOf((*MockStreamI)(nil).WaitUntilAcked), arg0)
+	return &StreamIWaitUntilAckedCall{Call: call}
+}
+
+// StreamIWaitUntilAckedCall wrap *gomock.Call
+type StreamIWaitUntilAckedCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *StreamIWaitUntilAckedCall) Return(arg0 error) *StreamIWaitUntilAckedCall {
+	c.Call = c.Call.Return(arg0)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *StreamIWaitUntilAckedCall) Do(f func(context.Context) error) *StreamIWaitUntilAckedCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *StreamIWaitUntilAckedCall) DoAndReturn(f func(context.Context) error) *StreamIWaitUntilAckedCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // Write mocks base method.
 func (m *MockStreamI) Write(arg0 []byte) (int, error) {
 	m.ctrl.T.Helper()
@@ -645,6 +721,46 @@ func (c *StreamIhasDataCall) DoAndReturn(f func() bool) *StreamIhasDataCall {
 	return c
 }
 
+// popRetransmission mocks base method.
+func (m *MockStreamI) popRetransmission(arg0 protocol.ByteCount, arg1 protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "popRetransmission", arg0, arg1)
+	ret0, _ := ret[0].(ackhandler.StreamFrame)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(bool)
+	return ret0, ret1, ret2
+}
+
+// popRetransmission indicates an expected call of popRetransmission.
+func (mr *MockStreamIMockRecorder) popRetransmission(arg0, arg1 any) *StreamIpopRetransmissionCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "popRetransmission", reflect.TypeOf((*MockStreamI)(nil).popRetransmission), arg0, arg1)
+	return &StreamIpopRetransmissionCall{Call: call}
+}
+
+// StreamIpopRetransmissionCall wrap *gomock.Call
+type StreamIpopRetransmissionCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *StreamIpopRetransmissionCall) Return(arg0 ackhandler.StreamFrame, arg1, arg2 bool) *StreamIpopRetransmissionCall {
+	c.Call = c.Call.Return(arg0, arg1, arg2)
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *StreamIpopRetransmissionCall) Do(f func(protocol.ByteCount, protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool)) *StreamIpopRetransmissionCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *StreamIpopRetransmissionCall) DoAndReturn(f func(protocol.ByteCount, protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool)) *StreamIpopRetransmissionCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // popStreamFrame mocks base method.
 func (m *MockStreamI) popStreamFrame(arg0 protocol.ByteCount, arg1 protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool) {
 	m.ctrl.T.Helper()
@@ -75,6 +75,42 @@ func (c *StreamSenderonHasStreamDataCall) DoAndReturn(f func(protocol.StreamID))
 	return c
 }
 
+// onHasStreamRetransmission mocks base method.
+func (m *MockStreamSender) onHasStreamRetransmission(arg0 protocol.StreamID) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "onHasStreamRetransmission", arg0)
+}
+
+// onHasStreamRetransmission indicates an expected call of onHasStreamRetransmission.
+func (mr *MockStreamSenderMockRecorder) onHasStreamRetransmission(arg0 any) *StreamSenderonHasStreamRetransmissionCall {
+	mr.mock.ctrl.T.Helper()
+	call := mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "onHasStreamRetransmission", reflect.TypeOf((*MockStreamSender)(nil).onHasStreamRetransmission), arg0)
+	return &StreamSenderonHasStreamRetransmissionCall{Call: call}
+}
+
+// StreamSenderonHasStreamRetransmissionCall wrap *gomock.Call
+type StreamSenderonHasStreamRetransmissionCall struct {
+	*gomock.Call
+}
+
+// Return rewrite *gomock.Call.Return
+func (c *StreamSenderonHasStreamRetransmissionCall) Return() *StreamSenderonHasStreamRetransmissionCall {
+	c.Call = c.Call.Return()
+	return c
+}
+
+// Do rewrite *gomock.Call.Do
+func (c *StreamSenderonHasStreamRetransmissionCall) Do(f func(protocol.StreamID)) *StreamSenderonHasStreamRetransmissionCall {
+	c.Call = c.Call.Do(f)
+	return c
+}
+
+// DoAndReturn rewrite *gomock.Call.DoAndReturn
+func (c *StreamSenderonHasStreamRetransmissionCall) DoAndReturn(f func(protocol.StreamID)) *StreamSenderonHasStreamRetransmissionCall {
+	c.Call = c.Call.DoAndReturn(f)
+	return c
+}
+
 // onStreamCompleted mocks base method.
 func (m *MockStreamSender) onStreamCompleted(arg0 protocol.StreamID) {
 	m.ctrl.T.Helper()
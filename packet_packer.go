@@ -17,6 +17,13 @@ import (
 
 var errNothingToPack = errors.New("nothing to pack")
 
+// errPacketNumberSpaceExhausted is returned when all packet numbers of the application data
+// packet number space have been used up.
+var errPacketNumberSpaceExhausted = &qerr.TransportError{
+	ErrorCode:    qerr.InternalError,
+	ErrorMessage: "packet number space exhausted",
+}
+
 type packer interface {
 	PackCoalescedPacket(onlyAck bool, maxPacketSize protocol.ByteCount, v protocol.VersionNumber) (*coalescedPacket, error)
 	PackAckOnlyPacket(maxPacketSize protocol.ByteCount, v protocol.VersionNumber) (shortHeaderPacket, *packetBuffer, error)
@@ -25,8 +32,10 @@ type packer interface {
 	PackConnectionClose(*qerr.TransportError, protocol.ByteCount, protocol.VersionNumber) (*coalescedPacket, error)
 	PackApplicationClose(*qerr.ApplicationError, protocol.ByteCount, protocol.VersionNumber) (*coalescedPacket, error)
 	PackMTUProbePacket(ping ackhandler.Frame, size protocol.ByteCount, v protocol.VersionNumber) (shortHeaderPacket, *packetBuffer, error)
+	PackPathProbePacket(connID protocol.ConnectionID, pathChallenge ackhandler.Frame, v protocol.VersionNumber) (shortHeaderPacket, *packetBuffer, error)
 
 	SetToken([]byte)
+	SetSpinBit(bool)
 }
 
 type sealer interface {
@@ -45,6 +54,7 @@ type longHeaderPacket struct {
 	ack          *wire.AckFrame
 	frames       []ackhandler.Frame
 	streamFrames []ackhandler.StreamFrame // only used for 0-RTT packets
+	rawFrames    [][]byte                 // only set if raw frames are recorded
 
 	length protocol.ByteCount
 }
@@ -61,6 +71,7 @@ type shortHeaderPacket struct {
 	DestConnID      protocol.ConnectionID
 	PacketNumberLen protocol.PacketNumberLen
 	KeyPhase        protocol.KeyPhaseBit
+	RawFrames       [][]byte // only set if raw frames are recorded
 }
 
 func (p *shortHeaderPacket) IsAckEliciting() bool { return ackhandler.HasAckElicitingFrames(p.Frames) }
@@ -126,11 +137,21 @@ type packetPacker struct {
 
 	token []byte
 
+	disableSpinBit bool
+	spinBit        bool
+
+	maxAckRanges int // maximum number of ACK ranges in an ACK frame for 1-RTT packets
+
+	// If set, the serialized frames are recorded, in the order they were packed.
+	recordRawFrames bool
+	frameOffsets    []frameOffset // positions of the frames in the last packet payload
+
 	pnManager           packetNumberManager
 	framer              frameSource
 	acks                ackFrameSource
 	datagramQueue       *datagramQueue
 	retransmissionQueue *retransmissionQueue
+	retireConnIDHandler ackhandler.FrameHandler
 	rand                rand.Rand
 
 	numNonAckElicitingAcks int
@@ -138,17 +159,26 @@ type packetPacker struct {
 
 var _ packer = &packetPacker{}
 
+// frameOffset is the position of a serialized frame in a packet
+type frameOffset struct {
+	start, end int
+}
+
 func newPacketPacker(
 	srcConnID protocol.ConnectionID,
 	getDestConnID func() protocol.ConnectionID,
 	initialStream, handshakeStream cryptoStream,
 	packetNumberManager packetNumberManager,
 	retransmissionQueue *retransmissionQueue,
+	retireConnIDHandler ackhandler.FrameHandler,
 	cryptoSetup sealingManager,
 	framer frameSource,
 	acks ackFrameSource,
 	datagramQueue *datagramQueue,
 	perspective protocol.Perspective,
+	disableSpinBit bool,
+	maxAckRanges int,
+	recordRawFrames bool,
 ) *packetPacker {
 	var b [8]byte
 	_, _ = crand.Read(b[:])
@@ -160,8 +190,12 @@ func newPacketPacker(
 		initialStream:       initialStream,
 		handshakeStream:     handshakeStream,
 		retransmissionQueue: retransmissionQueue,
+		retireConnIDHandler: retireConnIDHandler,
 		datagramQueue:       datagramQueue,
 		perspective:         perspective,
+		disableSpinBit:      disableSpinBit,
+		maxAckRanges:        maxAckRanges,
+		recordRawFrames:     recordRawFrames,
 		framer:              framer,
 		acks:                acks,
 		rand:                *rand.New(rand.NewSource(binary.BigEndian.Uint64(b[:]))),
@@ -581,9 +615,18 @@ func (p *packetPacker) maybeGetAppDataPacket(maxPayloadSize protocol.ByteCount,
 	return pl
 }
 
+// get1RTTAckFrame gets the ACK frame for 1-RTT packets, limited to the configured number of ACK ranges.
+func (p *packetPacker) get1RTTAckFrame(onlyIfQueued bool) *wire.AckFrame {
+	ack := p.acks.GetAckFrame(protocol.Encryption1RTT, onlyIfQueued)
+	if ack != nil {
+		ack.MaxNumRanges = p.maxAckRanges
+	}
+	return ack
+}
+
 func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, onlyAck, ackAllowed bool, v protocol.VersionNumber) payload {
 	if onlyAck {
-		if ack := p.acks.GetAckFrame(protocol.Encryption1RTT, true); ack != nil {
+		if ack := p.get1RTTAckFrame(true); ack != nil {
 			return payload{ack: ack, length: ack.Length(v)}
 		}
 		return payload{}
@@ -596,7 +639,7 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, onlyAc
 
 	var hasAck bool
 	if ackAllowed {
-		if ack := p.acks.GetAckFrame(protocol.Encryption1RTT, !hasRetransmission && !hasData); ack != nil {
+		if ack := p.get1RTTAckFrame(!hasRetransmission && !hasData); ack != nil {
 			pl.ack = ack
 			pl.length += ack.Length(v)
 			hasAck = true
@@ -642,8 +685,12 @@ func (p *packetPacker) composeNextPacket(maxFrameSize protocol.ByteCount, onlyAc
 		for i := startLen; i < len(pl.frames); i++ {
 			switch pl.frames[i].Frame.(type) {
 			case *wire.PathChallengeFrame, *wire.PathResponseFrame:
-				// Path probing is currently not supported, therefore we don't need to set the OnAcked callback yet.
-				// PATH_CHALLENGE and PATH_RESPONSE are never retransmitted.
+				// PATH_CHALLENGE and PATH_RESPONSE frames are never retransmitted, so we don't need to set the OnAcked callback.
+				// Path validation only relies on the receipt of the PATH_RESPONSE frame, not on the acknowledgement.
+				// PATH_CHALLENGE frames used for probing are sent in dedicated packets, see PackPathProbePacket.
+			case *wire.RetireConnectionIDFrame:
+				// The connection ID manager keeps track of RETIRE_CONNECTION_ID frames that haven't been acknowledged yet.
+				pl.frames[i].Handler = p.retireConnIDHandler
 			default:
 				pl.frames[i].Handler = p.retransmissionQueue.AppDataAckHandler()
 			}
@@ -739,6 +786,25 @@ func (p *packetPacker) PackMTUProbePacket(ping ackhandler.Frame, size protocol.B
 	return packet, buffer, err
 }
 
+// PackPathProbePacket packs a packet containing a PATH_CHALLENGE frame, using the connection ID connID.
+// RFC 9000, section 8.2.1: The packet is padded to at least 1200 bytes.
+func (p *packetPacker) PackPathProbePacket(connID protocol.ConnectionID, pathChallenge ackhandler.Frame, v protocol.VersionNumber) (shortHeaderPacket, *packetBuffer, error) {
+	pl := payload{
+		frames: []ackhandler.Frame{pathChallenge},
+		length: pathChallenge.Frame.Length(v),
+	}
+	buffer := getPacketBuffer()
+	s, err := p.cryptoSetup.Get1RTTSealer()
+	if err != nil {
+		return shortHeaderPacket{}, nil, err
+	}
+	pn, pnLen := p.pnManager.PeekPacketNumber(protocol.Encryption1RTT)
+	padding := protocol.MinInitialPacketSize - p.shortHeaderPacketLength(connID, pnLen, pl) - protocol.ByteCount(s.Overhead())
+	kp := s.KeyPhase()
+	packet, err := p.appendShortHeaderPacket(buffer, connID, pn, pnLen, kp, pl, padding, protocol.MinInitialPacketSize, s, false, v)
+	return packet, buffer, err
+}
+
 func (p *packetPacker) getLongHeader(encLevel protocol.EncryptionLevel, v protocol.VersionNumber) *wire.ExtendedHeader {
 	pn, pnLen := p.pnManager.PeekPacketNumber(encLevel)
 	hdr := &wire.ExtendedHeader{
@@ -783,6 +849,7 @@ func (p *packetPacker) appendLongHeaderPacket(buffer *packetBuffer, header *wire
 	if err != nil {
 		return nil, err
 	}
+	rawFrames := p.copyRawFrames(raw)
 	raw = p.encryptPacket(raw, sealer, header.PacketNumber, payloadOffset, pnLen)
 	buffer.Data = buffer.Data[:len(buffer.Data)+len(raw)]
 
@@ -794,6 +861,7 @@ func (p *packetPacker) appendLongHeaderPacket(buffer *packetBuffer, header *wire
 		ack:          pl.ack,
 		frames:       pl.frames,
 		streamFrames: pl.streamFrames,
+		rawFrames:    rawFrames,
 		length:       protocol.ByteCount(len(raw)),
 	}, nil
 }
@@ -810,6 +878,11 @@ func (p *packetPacker) appendShortHeaderPacket(
 	isMTUProbePacket bool,
 	v protocol.VersionNumber,
 ) (shortHeaderPacket, error) {
+	// RFC 9000, section 12.3: If the packet number for sending reaches 2^62-1,
+	// the sender MUST close the connection without sending a CONNECTION_CLOSE frame or any further packets.
+	if pn >= protocol.MaxPacketNumber {
+		return shortHeaderPacket{}, errPacketNumberSpaceExhausted
+	}
 	var paddingLen protocol.ByteCount
 	if pl.length < 4-protocol.ByteCount(pnLen) {
 		paddingLen = 4 - protocol.ByteCount(pnLen) - pl.length
@@ -822,6 +895,10 @@ func (p *packetPacker) appendShortHeaderPacket(
 	if err != nil {
 		return shortHeaderPacket{}, err
 	}
+	// The spin bit is not covered by header protection.
+	if p.getSpinBit() {
+		raw[0] |= 0x20
+	}
 	payloadOffset := protocol.ByteCount(len(raw))
 
 	raw, err = p.appendPacketPayload(raw, pl, paddingLen, v)
@@ -833,6 +910,7 @@ func (p *packetPacker) appendShortHeaderPacket(
 			return shortHeaderPacket{}, fmt.Errorf("PacketPacker BUG: packet too large (%d bytes, allowed %d bytes)", size, maxPacketSize)
 		}
 	}
+	rawFrames := p.copyRawFrames(raw)
 	raw = p.encryptPacket(raw, sealer, pn, payloadOffset, protocol.ByteCount(pnLen))
 	buffer.Data = buffer.Data[:len(buffer.Data)+len(raw)]
 
@@ -849,19 +927,24 @@ func (p *packetPacker) appendShortHeaderPacket(
 		Length:               protocol.ByteCount(len(raw)),
 		DestConnID:           connID,
 		IsPathMTUProbePacket: isMTUProbePacket,
+		RawFrames:            rawFrames,
 	}, nil
 }
 
 // appendPacketPayload serializes the payload of a packet into the raw byte slice.
 // It modifies the order of payload.frames.
+// If raw frames are recorded, it records the position of every frame in raw.
 func (p *packetPacker) appendPacketPayload(raw []byte, pl payload, paddingLen protocol.ByteCount, v protocol.VersionNumber) ([]byte, error) {
 	payloadOffset := len(raw)
+	p.frameOffsets = p.frameOffsets[:0]
 	if pl.ack != nil {
 		var err error
+		start := len(raw)
 		raw, err = pl.ack.Append(raw, v)
 		if err != nil {
 			return nil, err
 		}
+		p.recordFrameOffset(start, len(raw))
 	}
 	if paddingLen > 0 {
 		raw = append(raw, make([]byte, paddingLen)...)
@@ -873,17 +956,21 @@ func (p *packetPacker) appendPacketPayload(raw []byte, pl payload, paddingLen pr
 	}
 	for _, f := range pl.frames {
 		var err error
+		start := len(raw)
 		raw, err = f.Frame.Append(raw, v)
 		if err != nil {
 			return nil, err
 		}
+		p.recordFrameOffset(start, len(raw))
 	}
 	for _, f := range pl.streamFrames {
 		var err error
+		start := len(raw)
 		raw, err = f.Frame.Append(raw, v)
 		if err != nil {
 			return nil, err
 		}
+		p.recordFrameOffset(start, len(raw))
 	}
 
 	if payloadSize := protocol.ByteCount(len(raw)-payloadOffset) - paddingLen; payloadSize != pl.length {
@@ -892,6 +979,28 @@ func (p *packetPacker) appendPacketPayload(raw []byte, pl payload, paddingLen pr
 	return raw, nil
 }
 
+func (p *packetPacker) recordFrameOffset(start, end int) {
+	if p.recordRawFrames {
+		p.frameOffsets = append(p.frameOffsets, frameOffset{start: start, end: end})
+	}
+}
+
+// copyRawFrames copies the frames serialized by the last call to appendPacketPayload.
+// It must be called before the packet is encrypted in place.
+func (p *packetPacker) copyRawFrames(raw []byte) [][]byte {
+	if len(p.frameOffsets) == 0 {
+		return nil
+	}
+	first := p.frameOffsets[0].start
+	data := make([]byte, p.frameOffsets[len(p.frameOffsets)-1].end-first)
+	copy(data, raw[first:])
+	rawFrames := make([][]byte, 0, len(p.frameOffsets))
+	for _, o := range p.frameOffsets {
+		rawFrames = append(rawFrames, data[o.start-first:o.end-first:o.end-first])
+	}
+	return rawFrames
+}
+
 func (p *packetPacker) encryptPacket(raw []byte, sealer sealer, pn protocol.PacketNumber, payloadOffset, pnLen protocol.ByteCount) []byte {
 	_ = sealer.Seal(raw[payloadOffset:payloadOffset], raw[payloadOffset:], pn, raw[:payloadOffset])
 	raw = raw[:len(raw)+sealer.Overhead()]
@@ -904,3 +1013,18 @@ func (p *packetPacker) encryptPacket(raw []byte, sealer sealer, pn protocol.Pack
 func (p *packetPacker) SetToken(token []byte) {
 	p.token = token
 }
+
+// SetSpinBit sets the value of the spin bit used on short header packets.
+// It has no effect if the spin bit is disabled.
+func (p *packetPacker) SetSpinBit(spin bool) {
+	p.spinBit = spin
+}
+
+func (p *packetPacker) getSpinBit() bool {
+	// RFC 9000, section 17.4: If the spin bit is disabled,
+	// it is RECOMMENDED to set it to a random value chosen independently for each packet.
+	if p.disableSpinBit {
+		return p.rand.Intn(2) == 0
+	}
+	return p.spinBit
+}
@@ -30,6 +30,7 @@ var _ = Describe("Packet packer", func() {
 	var (
 		packer              *packetPacker
 		retransmissionQueue *retransmissionQueue
+		retireConnIDHandler ackhandler.FrameHandler
 		datagramQueue       *datagramQueue
 		framer              *MockFrameSource
 		ackFramer           *MockAckFrameSource
@@ -87,6 +88,7 @@ var _ = Describe("Packet packer", func() {
 	BeforeEach(func() {
 		rand.Seed(uint64(GinkgoRandomSeed()))
 		retransmissionQueue = newRetransmissionQueue()
+		retireConnIDHandler = newConnIDManager(connID, func(protocol.StatelessResetToken) {}, func(protocol.StatelessResetToken) {}, func(wire.Frame) {}).RetirementAckHandler()
 		mockSender := NewMockStreamSender(mockCtrl)
 		mockSender.EXPECT().onHasStreamData(gomock.Any()).AnyTimes()
 		initialStream = NewMockCryptoStream(mockCtrl)
@@ -97,7 +99,7 @@ var _ = Describe("Packet packer", func() {
 		pnManager = mockackhandler.NewMockSentPacketHandler(mockCtrl)
 		datagramQueue = newDatagramQueue(func() {}, utils.DefaultLogger)
 
-		packer = newPacketPacker(protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), func() protocol.ConnectionID { return connID }, initialStream, handshakeStream, pnManager, retransmissionQueue, sealingManager, framer, ackFramer, datagramQueue, protocol.PerspectiveServer)
+		packer = newPacketPacker(protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8}), func() protocol.ConnectionID { return connID }, initialStream, handshakeStream, pnManager, retransmissionQueue, retireConnIDHandler, sealingManager, framer, ackFramer, datagramQueue, protocol.PerspectiveServer, false, protocol.MaxNumAckRanges, false)
 	})
 
 	Context("determining the maximum packet size", func() {
@@ -282,6 +284,44 @@ var _ = Describe("Packet packer", func() {
 			})
 		})
 
+		Context("spin bit", func() {
+			// packAckOnly packs a 1-RTT ACK-only packet, and returns the value of the spin bit
+			packAckOnly := func() bool {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true).Return(&wire.AckFrame{AckRanges: []wire.AckRange{{Smallest: 1, Largest: 10}}})
+				_, buffer, err := packer.PackAckOnlyPacket(maxPacketSize, protocol.Version1)
+				Expect(err).NotTo(HaveOccurred())
+				parseShortHeaderPacket(buffer.Data)
+				return buffer.Data[0]&0x20 > 0
+			}
+
+			It("sets the spin bit", func() {
+				Expect(packAckOnly()).To(BeFalse())
+				packer.SetSpinBit(true)
+				Expect(packAckOnly()).To(BeTrue())
+				Expect(packAckOnly()).To(BeTrue())
+				packer.SetSpinBit(false)
+				Expect(packAckOnly()).To(BeFalse())
+			})
+
+			It("randomizes the spin bit, if the spin bit is disabled", func() {
+				packer.disableSpinBit = true
+				packer.SetSpinBit(true)
+				var numSet int
+				for i := 0; i < 200; i++ {
+					if packAckOnly() {
+						numSet++
+					}
+				}
+				Expect(numSet).To(And(
+					BeNumerically(">", 50),
+					BeNumerically("<", 150),
+				))
+			})
+		})
+
 		Context("packing 0-RTT packets", func() {
 			BeforeEach(func() {
 				packer.perspective = protocol.PerspectiveClient
@@ -349,6 +389,31 @@ var _ = Describe("Packet packer", func() {
 				Expect(ccf.ReasonPhrase).To(BeEmpty())
 			})
 
+			It("packs a CONNECTION_CLOSE in Initial, if that's the only encryption level available", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().GetInitialSealer().Return(getSealer(), nil)
+				sealingManager.EXPECT().GetHandshakeSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				sealingManager.EXPECT().Get1RTTSealer().Return(nil, handshake.ErrKeysNotYetAvailable)
+				// the handshake failed
+				quicErr := qerr.NewLocalCryptoError(0x28, errors.New("handshake failure"))
+				p, err := packer.PackConnectionClose(quicErr, maxPacketSize, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.shortHdrPacket).To(BeNil())
+				Expect(p.longHdrPackets).To(HaveLen(1))
+				Expect(p.longHdrPackets[0].header.Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(p.longHdrPackets[0].header.PacketNumber).To(Equal(protocol.PacketNumber(0x42)))
+				Expect(p.longHdrPackets[0].frames).To(HaveLen(1))
+				Expect(p.longHdrPackets[0].frames[0].Frame).To(BeAssignableToTypeOf(&wire.ConnectionCloseFrame{}))
+				ccf := p.longHdrPackets[0].frames[0].Frame.(*wire.ConnectionCloseFrame)
+				Expect(ccf.IsApplicationError).To(BeFalse())
+				Expect(ccf.ErrorCode).To(BeEquivalentTo(0x100 + 0x28))
+				hdrs, more := parsePacket(p.buffer.Data)
+				Expect(hdrs).To(HaveLen(1))
+				Expect(hdrs[0].Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(more).To(BeEmpty())
+			})
+
 			It("packs a CONNECTION_CLOSE in 1-RTT", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
@@ -526,6 +591,41 @@ var _ = Describe("Packet packer", func() {
 				Expect(buffer.Data).To(ContainSubstring(string(b)))
 			})
 
+			It("bundles a pending ACK with STREAM frames", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData().Return(true)
+				// Since we're sending a packet anyway, the ACK frame is included even if it's not queued yet.
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Largest: 42, Smallest: 1}}}
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false).Return(ack)
+				expectAppendControlFrames()
+				f := &wire.StreamFrame{
+					StreamID: 5,
+					Data:     []byte("foobar"),
+				}
+				expectAppendStreamFrames(ackhandler.StreamFrame{Frame: f})
+				p, err := packer.AppendPacket(getPacketBuffer(), maxPacketSize, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.Ack).To(Equal(ack))
+				Expect(p.StreamFrames).To(HaveLen(1))
+				Expect(p.StreamFrames[0].Frame).To(Equal(f))
+			})
+
+			It("refuses to pack a packet when the packet number space is exhausted", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.MaxPacketNumber, protocol.PacketNumberLen4)
+				// don't expect any calls to PopPacketNumber
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData().Return(true)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				expectAppendControlFrames()
+				expectAppendStreamFrames(ackhandler.StreamFrame{Frame: &wire.StreamFrame{StreamID: 5, Data: []byte("foobar")}})
+				buffer := getPacketBuffer()
+				_, err := packer.AppendPacket(buffer, maxPacketSize, protocol.Version1)
+				Expect(err).To(MatchError(errPacketNumberSpaceExhausted))
+				Expect(buffer.Data).To(BeEmpty())
+			})
+
 			It("packs a single ACK", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
@@ -539,6 +639,24 @@ var _ = Describe("Packet packer", func() {
 				Expect(p.Ack).To(Equal(ack))
 			})
 
+			It("limits the number of ACK ranges", func() {
+				packer.maxAckRanges = 2
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Largest: 42, Smallest: 40}, {Largest: 30, Smallest: 20}, {Largest: 10, Smallest: 1}}}
+				framer.EXPECT().HasData()
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true).Return(ack)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				buffer := getPacketBuffer()
+				p, err := packer.AppendPacket(buffer, maxPacketSize, protocol.Version1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(p.Ack.MaxNumRanges).To(Equal(2))
+				hdrLen := 1 + p.DestConnID.Len() + int(p.PacketNumberLen)
+				_, frame, err := wire.NewFrameParser(false).ParseNext(buffer.Data[hdrLen:], protocol.Encryption1RTT, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(frame.(*wire.AckFrame).AckRanges).To(Equal(ack.AckRanges[:2]))
+			})
+
 			It("packs control frames", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
@@ -562,6 +680,58 @@ var _ = Describe("Packet packer", func() {
 				Expect(buffer.Len()).ToNot(BeZero())
 			})
 
+			It("records the raw frames", func() {
+				packer.recordRawFrames = true
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				// use a sealer that actually modifies the payload
+				sealer := mocks.NewMockShortHeaderSealer(mockCtrl)
+				sealer.EXPECT().KeyPhase().Return(protocol.KeyPhaseOne).AnyTimes()
+				sealer.EXPECT().Overhead().Return(7).AnyTimes()
+				sealer.EXPECT().EncryptHeader(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+				sealer.EXPECT().Seal(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(dst, src []byte, pn protocol.PacketNumber, associatedData []byte) []byte {
+					for i := range src {
+						src[i] ^= 0xff
+					}
+					return append(src, bytes.Repeat([]byte{'s'}, sealer.Overhead())...)
+				})
+				sealingManager.EXPECT().Get1RTTSealer().Return(sealer, nil)
+				framer.EXPECT().HasData().Return(true)
+				ack := &wire.AckFrame{AckRanges: []wire.AckRange{{Largest: 42, Smallest: 1}}}
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false).Return(ack)
+				expectAppendControlFrames(ackhandler.Frame{Frame: &wire.MaxDataFrame{MaximumData: 1337}}, ackhandler.Frame{Frame: &wire.PingFrame{}})
+				expectAppendStreamFrames(ackhandler.StreamFrame{Frame: &wire.StreamFrame{StreamID: 5, Offset: 100, Data: []byte("foobar")}})
+				p, err := packer.AppendPacket(getPacketBuffer(), maxPacketSize, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				// The raw frames are recorded in the order the frames were packed.
+				frames := []wire.Frame{p.Ack}
+				for _, f := range p.Frames {
+					frames = append(frames, f.Frame)
+				}
+				for _, f := range p.StreamFrames {
+					frames = append(frames, f.Frame)
+				}
+				Expect(p.RawFrames).To(HaveLen(len(frames)))
+				for i, f := range frames {
+					b, err := f.Append(nil, protocol.Version1)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(p.RawFrames[i]).To(Equal(b))
+				}
+			})
+
+			It("doesn't record raw frames by default", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData().Return(true)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				expectAppendControlFrames(ackhandler.Frame{Frame: &wire.PingFrame{}})
+				expectAppendStreamFrames()
+				p, err := packer.AppendPacket(getPacketBuffer(), maxPacketSize, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.RawFrames).To(BeNil())
+			})
+
 			It("packs PATH_CHALLENGE and PATH_RESPONSE frames", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
@@ -593,6 +763,30 @@ var _ = Describe("Packet packer", func() {
 				Expect(buffer.Len()).ToNot(BeZero())
 			})
 
+			It("uses the connection ID manager's handler for RETIRE_CONNECTION_ID frames", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42))
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData().Return(true)
+				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, false)
+				expectAppendControlFrames(
+					ackhandler.Frame{Frame: &wire.RetireConnectionIDFrame{SequenceNumber: 3}},
+					ackhandler.Frame{Frame: &wire.MaxDataFrame{}},
+				)
+				expectAppendStreamFrames()
+				p, err := packer.AppendPacket(getPacketBuffer(), maxPacketSize, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.Frames).To(HaveLen(2))
+				for _, f := range p.Frames {
+					switch f.Frame.(type) {
+					case *wire.RetireConnectionIDFrame:
+						Expect(f.Handler).To(Equal(retireConnIDHandler))
+					default:
+						Expect(f.Handler).To(Equal(retransmissionQueue.AppDataAckHandler()))
+					}
+				}
+			})
+
 			It("packs DATAGRAM frames", func() {
 				ackFramer.EXPECT().GetAckFrame(protocol.Encryption1RTT, true)
 				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
@@ -1170,6 +1364,45 @@ var _ = Describe("Packet packer", func() {
 				parseShortHeaderPacket(more)
 			})
 
+			It("packs a coalesced packet with Initial / Handshake / 1-RTT, in increasing encryption level order", func() {
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionInitial).Return(protocol.PacketNumber(0x24))
+				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x42))
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x1337))
+				sealingManager.EXPECT().GetInitialSealer().Return(getSealer(), nil)
+				sealingManager.EXPECT().GetHandshakeSealer().Return(getSealer(), nil)
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				framer.EXPECT().HasData().Return(true)
+				ackFramer.EXPECT().GetAckFrame(protocol.EncryptionInitial, false)
+				// don't EXPECT any calls for a Handshake or 1-RTT ACK frame
+				initialStream.EXPECT().HasData().Return(true).Times(2)
+				initialStream.EXPECT().PopCryptoFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) *wire.CryptoFrame {
+					return &wire.CryptoFrame{Offset: 0x42, Data: []byte("initial")}
+				})
+				handshakeStream.EXPECT().HasData().Return(true).Times(2)
+				handshakeStream.EXPECT().PopCryptoFrame(gomock.Any()).DoAndReturn(func(size protocol.ByteCount) *wire.CryptoFrame {
+					return &wire.CryptoFrame{Offset: 0x1337, Data: []byte("handshake")}
+				})
+				expectAppendControlFrames()
+				expectAppendStreamFrames(ackhandler.StreamFrame{Frame: &wire.StreamFrame{Data: []byte("foobar")}})
+				p, err := packer.PackCoalescedPacket(false, maxPacketSize, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.longHdrPackets).To(HaveLen(2))
+				Expect(p.longHdrPackets[0].EncryptionLevel()).To(Equal(protocol.EncryptionInitial))
+				Expect(p.longHdrPackets[1].EncryptionLevel()).To(Equal(protocol.EncryptionHandshake))
+				Expect(p.shortHdrPacket).ToNot(BeNil())
+				Expect(p.shortHdrPacket.StreamFrames).To(HaveLen(1))
+				// the packets appear in the datagram in this order: Initial, Handshake, 1-RTT
+				hdrs, more := parsePacket(p.buffer.Data)
+				Expect(hdrs).To(HaveLen(2))
+				Expect(hdrs[0].Type).To(Equal(protocol.PacketTypeInitial))
+				Expect(hdrs[1].Type).To(Equal(protocol.PacketTypeHandshake))
+				Expect(more).ToNot(BeEmpty())
+				parseShortHeaderPacket(more)
+			})
+
 			It("doesn't add a coalesced packet if the remaining size is smaller than MaxCoalescedPacketSize", func() {
 				pnManager.EXPECT().PeekPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x24), protocol.PacketNumberLen2)
 				pnManager.EXPECT().PopPacketNumber(protocol.EncryptionHandshake).Return(protocol.PacketNumber(0x24))
@@ -1516,6 +1749,23 @@ var _ = Describe("Packet packer", func() {
 				Expect(buffer.Data).To(HaveLen(int(probePacketSize)))
 				Expect(p.IsPathMTUProbePacket).To(BeTrue())
 			})
+
+			It("packs a path probe packet", func() {
+				sealingManager.EXPECT().Get1RTTSealer().Return(getSealer(), nil)
+				pnManager.EXPECT().PeekPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43), protocol.PacketNumberLen2)
+				pnManager.EXPECT().PopPacketNumber(protocol.Encryption1RTT).Return(protocol.PacketNumber(0x43))
+				connID := protocol.ParseConnectionID([]byte{0xde, 0xca, 0xfb, 0xad})
+				pathChallenge := ackhandler.Frame{Frame: &wire.PathChallengeFrame{Data: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}}}
+				p, buffer, err := packer.PackPathProbePacket(connID, pathChallenge, protocol.Version1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(p.Length).To(BeEquivalentTo(protocol.MinInitialPacketSize))
+				Expect(p.PacketNumber).To(Equal(protocol.PacketNumber(0x43)))
+				Expect(p.DestConnID).To(Equal(connID))
+				Expect(p.Frames).To(Equal([]ackhandler.Frame{pathChallenge}))
+				Expect(p.IsPathMTUProbePacket).To(BeFalse())
+				Expect(buffer.Data).To(HaveLen(protocol.MinInitialPacketSize))
+				Expect(buffer.Data[1 : 1+connID.Len()]).To(Equal(connID.Bytes()))
+			})
 		})
 	})
 })
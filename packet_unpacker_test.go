@@ -218,6 +218,40 @@ var _ = Describe("Packet Unpacker", func() {
 		Expect(err).To(MatchError(unpackErr))
 	})
 
+	It("only uses the 1-RTT keys for short header packets", func() {
+		hdrRaw := getShortHeader(connID, 0x42, protocol.PacketNumberLen2, protocol.KeyPhaseZero)
+		opener := mocks.NewMockShortHeaderOpener(mockCtrl)
+		// don't EXPECT any calls to GetInitialOpener, GetHandshakeOpener or Get0RTTOpener
+		cs.EXPECT().Get1RTTOpener().Return(opener, nil)
+		opener.EXPECT().DecryptHeader(gomock.Any(), gomock.Any(), gomock.Any())
+		opener.EXPECT().DecodePacketNumber(gomock.Any(), gomock.Any())
+		opener.EXPECT().Open(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
+		_, _, _, _, err := unpacker.UnpackShortHeader(time.Now(), append(hdrRaw, payload...))
+		Expect(err).To(MatchError(handshake.ErrDecryptionFailed))
+	})
+
+	It("only uses the Handshake keys for Handshake packets", func() {
+		extHdr := &wire.ExtendedHeader{
+			Header: wire.Header{
+				Type:             protocol.PacketTypeHandshake,
+				Length:           3, // packet number len
+				DestConnectionID: connID,
+				Version:          protocol.Version1,
+			},
+			PacketNumber:    2,
+			PacketNumberLen: 3,
+		}
+		hdr, hdrRaw := getLongHeader(extHdr)
+		opener := mocks.NewMockLongHeaderOpener(mockCtrl)
+		// don't EXPECT any calls to GetInitialOpener, Get0RTTOpener or Get1RTTOpener
+		cs.EXPECT().GetHandshakeOpener().Return(opener, nil)
+		opener.EXPECT().DecryptHeader(gomock.Any(), gomock.Any(), gomock.Any())
+		opener.EXPECT().DecodePacketNumber(gomock.Any(), gomock.Any())
+		opener.EXPECT().Open(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, handshake.ErrDecryptionFailed)
+		_, err := unpacker.UnpackLongHeader(hdr, time.Now(), append(hdrRaw, payload...), protocol.Version1)
+		Expect(err).To(MatchError(handshake.ErrDecryptionFailed))
+	})
+
 	It("defends against the timing side-channel when the reserved bits are wrong, for long header packets", func() {
 		extHdr := &wire.ExtendedHeader{
 			Header: wire.Header{
@@ -115,6 +115,8 @@ func (e eventConnectionClosed) MarshalJSONObject(enc *gojay.Encoder) {
 	var (
 		statelessResetErr     *quic.StatelessResetError
 		handshakeTimeoutErr   *quic.HandshakeTimeoutError
+		handshakeNoProgress   *quic.HandshakeNoProgressError
+		maxConnDurationErr    *quic.MaxConnectionDurationError
 		idleTimeoutErr        *quic.IdleTimeoutError
 		applicationErr        *quic.ApplicationError
 		transportErr          *quic.TransportError
@@ -128,6 +130,12 @@ func (e eventConnectionClosed) MarshalJSONObject(enc *gojay.Encoder) {
 	case errors.As(e.e, &handshakeTimeoutErr):
 		enc.StringKey("owner", ownerLocal.String())
 		enc.StringKey("trigger", "handshake_timeout")
+	case errors.As(e.e, &handshakeNoProgress):
+		enc.StringKey("owner", ownerLocal.String())
+		enc.StringKey("trigger", "handshake_no_progress")
+	case errors.As(e.e, &maxConnDurationErr):
+		enc.StringKey("owner", ownerLocal.String())
+		enc.StringKey("trigger", "max_connection_duration")
 	case errors.As(e.e, &idleTimeoutErr):
 		enc.StringKey("owner", ownerLocal.String())
 		enc.StringKey("trigger", "idle_timeout")
@@ -74,6 +74,11 @@ func (s *receiveStream) StreamID() protocol.StreamID {
 	return s.streamID
 }
 
+// flowControlState returns the state of the stream's flow controller, for debugging purposes
+func (s *receiveStream) flowControlState() flowcontrol.State {
+	return s.flowController.State()
+}
+
 // Read implements io.Reader. It is not thread safe!
 func (s *receiveStream) Read(p []byte) (int, error) {
 	// Concurrent use of Read is not permitted (and doesn't make any sense),
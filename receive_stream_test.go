@@ -8,8 +8,11 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/mocks"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/qerr"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -406,6 +409,34 @@ var _ = Describe("Receive Stream", func() {
 					Expect(err).To(MatchError(io.EOF))
 				})
 
+				It("accepts retransmissions of the FIN at the same final size", func() {
+					connFC := flowcontrol.NewConnectionFlowController(1000, 1000, nil, func(protocol.ByteCount) bool { return true }, &utils.RTTStats{}, utils.DefaultLogger)
+					str := newReceiveStream(streamID, mockSender, flowcontrol.NewStreamFlowController(streamID, connFC, 1000, 1000, 1000, nil, &utils.RTTStats{}, utils.DefaultLogger))
+					Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 0, Data: []byte("foo")})).To(Succeed())
+					Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar"), Fin: true})).To(Succeed())
+					// retransmissions of the FIN-bearing frame, as well as an empty frame with the FIN bit
+					Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar"), Fin: true})).To(Succeed())
+					Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 6, Fin: true})).To(Succeed())
+					mockSender.EXPECT().onStreamCompleted(streamID)
+					data, err := io.ReadAll(str)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(data).To(Equal([]byte("foobar")))
+					// the FIN is retransmitted again after the application read all data
+					Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 3, Data: []byte("bar"), Fin: true})).To(Succeed())
+				})
+
+				It("errors when receiving a FIN at a different final size", func() {
+					connFC := flowcontrol.NewConnectionFlowController(1000, 1000, nil, func(protocol.ByteCount) bool { return true }, &utils.RTTStats{}, utils.DefaultLogger)
+					str := newReceiveStream(streamID, mockSender, flowcontrol.NewStreamFlowController(streamID, connFC, 1000, 1000, 1000, nil, &utils.RTTStats{}, utils.DefaultLogger))
+					Expect(str.handleStreamFrame(&wire.StreamFrame{Offset: 0, Data: []byte("foobar"), Fin: true})).To(Succeed())
+					err := str.handleStreamFrame(&wire.StreamFrame{Offset: 0, Data: []byte("foo"), Fin: true})
+					Expect(err).To(HaveOccurred())
+					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FinalSizeError))
+					err = str.handleStreamFrame(&wire.StreamFrame{Offset: 6, Data: []byte("baz"), Fin: true})
+					Expect(err).To(HaveOccurred())
+					Expect(err.(*qerr.TransportError).ErrorCode).To(Equal(qerr.FinalSizeError))
+				})
+
 				// Calling Read concurrently doesn't make any sense (and is forbidden),
 				// but we still want to make sure that we don't complete the stream more than once
 				// if the user misuses our API.
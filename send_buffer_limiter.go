@@ -0,0 +1,70 @@
+package quic
+
+import (
+	"sync"
+
+	"github.com/quic-go/quic-go/internal/protocol"
+)
+
+// The sendBufferLimiter limits the total number of bytes that were sent on streams,
+// but haven't been acknowledged by the peer yet.
+// Streams that are blocked by the limiter are notified once enough data has been acknowledged.
+type sendBufferLimiter struct {
+	mutex sync.Mutex
+
+	limit protocol.ByteCount
+	used  protocol.ByteCount
+
+	blocked     map[protocol.StreamID]struct{} // used as a set
+	onUnblocked func(protocol.StreamID)
+}
+
+func newSendBufferLimiter(limit protocol.ByteCount, onUnblocked func(protocol.StreamID)) *sendBufferLimiter {
+	return &sendBufferLimiter{
+		limit:       limit,
+		blocked:     make(map[protocol.StreamID]struct{}),
+		onUnblocked: onUnblocked,
+	}
+}
+
+// Available returns the number of bytes that can be sent.
+// If no bytes can be sent, the stream is notified once bytes become available.
+func (l *sendBufferLimiter) Available(id protocol.StreamID) protocol.ByteCount {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.used >= l.limit {
+		l.blocked[id] = struct{}{}
+		return 0
+	}
+	return l.limit - l.used
+}
+
+// Add adds bytes that were sent.
+func (l *sendBufferLimiter) Add(n protocol.ByteCount) {
+	l.mutex.Lock()
+	l.used += n
+	l.mutex.Unlock()
+}
+
+// Release releases bytes that were acknowledged, or that won't be retransmitted.
+func (l *sendBufferLimiter) Release(n protocol.ByteCount) {
+	l.mutex.Lock()
+	l.used -= n
+	if l.used < 0 {
+		panic("sendBufferLimiter: released more bytes than were added")
+	}
+	var unblocked []protocol.StreamID
+	if l.used < l.limit && len(l.blocked) > 0 {
+		unblocked = make([]protocol.StreamID, 0, len(l.blocked))
+		for id := range l.blocked {
+			unblocked = append(unblocked, id)
+			delete(l.blocked, id)
+		}
+	}
+	l.mutex.Unlock()
+
+	for _, id := range unblocked {
+		l.onUnblocked(id)
+	}
+}
@@ -0,0 +1,57 @@
+package quic
+
+import (
+	"github.com/quic-go/quic-go/internal/protocol"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Send Buffer Limiter", func() {
+	var (
+		l         *sendBufferLimiter
+		unblocked []protocol.StreamID
+	)
+
+	BeforeEach(func() {
+		unblocked = nil
+		l = newSendBufferLimiter(1000, func(id protocol.StreamID) { unblocked = append(unblocked, id) })
+	})
+
+	It("returns the number of bytes that can be sent", func() {
+		Expect(l.Available(4)).To(Equal(protocol.ByteCount(1000)))
+		l.Add(300)
+		Expect(l.Available(4)).To(Equal(protocol.ByteCount(700)))
+		l.Release(100)
+		Expect(l.Available(4)).To(Equal(protocol.ByteCount(800)))
+		Expect(unblocked).To(BeEmpty())
+	})
+
+	It("unblocks streams when bytes are released", func() {
+		l.Add(1000)
+		Expect(l.Available(4)).To(BeZero())
+		Expect(l.Available(8)).To(BeZero())
+		Expect(l.Available(8)).To(BeZero())
+		l.Release(10)
+		Expect(unblocked).To(ConsistOf(protocol.StreamID(4), protocol.StreamID(8)))
+		Expect(l.Available(4)).To(Equal(protocol.ByteCount(10)))
+		// streams are only unblocked once
+		unblocked = nil
+		l.Release(10)
+		Expect(unblocked).To(BeEmpty())
+	})
+
+	It("doesn't unblock streams if the buffer is still full", func() {
+		l.Add(1200)
+		Expect(l.Available(4)).To(BeZero())
+		l.Release(100)
+		Expect(unblocked).To(BeEmpty())
+		l.Release(200)
+		Expect(unblocked).To(Equal([]protocol.StreamID{4}))
+	})
+
+	It("panics when releasing more bytes than were added", func() {
+		l.Add(100)
+		Expect(func() { l.Release(101) }).To(Panic())
+	})
+})
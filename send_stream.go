@@ -19,6 +19,7 @@ type sendStreamI interface {
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	hasData() bool
 	popStreamFrame(maxBytes protocol.ByteCount, v protocol.VersionNumber) (frame ackhandler.StreamFrame, ok, hasMore bool)
+	popRetransmission(maxBytes protocol.ByteCount, v protocol.VersionNumber) (frame ackhandler.StreamFrame, ok, hasMore bool)
 	closeForShutdown(error)
 	updateSendWindow(protocol.ByteCount)
 }
@@ -51,7 +52,12 @@ type sendStream struct {
 	writeOnce chan struct{}
 	deadline  time.Time
 
+	doneChan chan struct{} // closed once the stream is completed, canceled, or closed for shutdown
+
 	flowController flowcontrol.StreamFlowController
+
+	sendBuffer   *sendBufferLimiter // nil if the send buffer size is not limited
+	unackedBytes protocol.ByteCount // bytes sent, but not acknowledged yet
 }
 
 var (
@@ -63,13 +69,16 @@ func newSendStream(
 	streamID protocol.StreamID,
 	sender streamSender,
 	flowController flowcontrol.StreamFlowController,
+	sendBuffer *sendBufferLimiter,
 ) *sendStream {
 	s := &sendStream{
 		streamID:       streamID,
 		sender:         sender,
 		flowController: flowController,
+		sendBuffer:     sendBuffer,
 		writeChan:      make(chan struct{}, 1),
 		writeOnce:      make(chan struct{}, 1), // cap: 1, to protect against concurrent use of Write
+		doneChan:       make(chan struct{}),
 	}
 	s.ctx, s.ctxCancel = context.WithCancelCause(context.Background())
 	return s
@@ -79,6 +88,11 @@ func (s *sendStream) StreamID() protocol.StreamID {
 	return s.streamID // same for receiveStream and sendStream
 }
 
+// flowControlState returns the state of the stream's flow controller, for debugging purposes
+func (s *sendStream) flowControlState() flowcontrol.State {
+	return s.flowController.State()
+}
+
 func (s *sendStream) Write(p []byte) (int, error) {
 	// Concurrent use of Write is not permitted (and doesn't make any sense),
 	// but sometimes people do it anyway.
@@ -215,6 +229,29 @@ func (s *sendStream) popStreamFrame(maxBytes protocol.ByteCount, v protocol.Vers
 	}, true, hasMoreData
 }
 
+// popRetransmission returns the next STREAM frame that needs to be retransmitted on this stream.
+// In contrast to popStreamFrame, it never returns new data.
+func (s *sendStream) popRetransmission(maxBytes protocol.ByteCount, v protocol.VersionNumber) (af ackhandler.StreamFrame, ok, hasMore bool) {
+	s.mutex.Lock()
+	if s.cancelWriteErr != nil || s.closeForShutdownErr != nil || len(s.retransmissionQueue) == 0 {
+		s.mutex.Unlock()
+		return ackhandler.StreamFrame{}, false, false
+	}
+	f, hasMoreRetransmissions := s.maybeGetRetransmission(maxBytes, v)
+	if f != nil {
+		s.numOutstandingFrames++
+	}
+	s.mutex.Unlock()
+
+	if f == nil {
+		return ackhandler.StreamFrame{}, false, hasMoreRetransmissions
+	}
+	return ackhandler.StreamFrame{
+		Frame:   f,
+		Handler: (*sendStreamAckHandler)(s),
+	}, true, hasMoreRetransmissions
+}
+
 func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCount, v protocol.VersionNumber) (*wire.StreamFrame, bool /* has more data to send */) {
 	if s.cancelWriteErr != nil || s.closeForShutdownErr != nil {
 		return nil, false
@@ -256,11 +293,23 @@ func (s *sendStream) popNewOrRetransmittedStreamFrame(maxBytes protocol.ByteCoun
 		}
 		return nil, true
 	}
+	if s.sendBuffer != nil {
+		// If the send buffer is full, we'll be notified once data is acknowledged.
+		available := s.sendBuffer.Available(s.streamID)
+		if available == 0 {
+			return nil, false
+		}
+		sendWindow = min(sendWindow, available)
+	}
 
 	f, hasMoreData := s.popNewStreamFrame(maxBytes, sendWindow, v)
 	if dataLen := f.DataLen(); dataLen > 0 {
 		s.writeOffset += f.DataLen()
 		s.flowController.AddBytesSent(f.DataLen())
+		s.unackedBytes += dataLen
+		if s.sendBuffer != nil {
+			s.sendBuffer.Add(dataLen)
+		}
 	}
 	f.Fin = s.finishedWriting && s.dataForWriting == nil && s.nextFrame == nil && !s.finSent
 	if f.Fin {
@@ -351,6 +400,7 @@ func (s *sendStream) isNewlyCompleted() bool {
 	completed := (s.finSent || s.cancelWriteErr != nil) && s.numOutstandingFrames == 0 && len(s.retransmissionQueue) == 0
 	if completed && !s.completed {
 		s.completed = true
+		s.signalDone()
 		return true
 	}
 	return false
@@ -387,15 +437,28 @@ func (s *sendStream) cancelWriteImpl(errorCode qerr.StreamErrorCode, remote bool
 	}
 	s.cancelWriteErr = &StreamError{StreamID: s.streamID, ErrorCode: errorCode, Remote: remote}
 	s.ctxCancel(s.cancelWriteErr)
+	// Unblock WaitUntilAcked: the remaining data will never be acknowledged.
+	s.signalDone()
 	s.numOutstandingFrames = 0
 	s.retransmissionQueue = nil
+	// Data that was buffered, but not sent yet, is discarded.
+	// The final size is the number of bytes that were actually sent.
+	if s.nextFrame != nil {
+		s.nextFrame.PutBack()
+		s.nextFrame = nil
+	}
+	finalSize := s.writeOffset
 	newlyCompleted := s.isNewlyCompleted()
+	released := s.releaseUnackedBytes()
 	s.mutex.Unlock()
 
+	if released > 0 {
+		s.sendBuffer.Release(released)
+	}
 	s.signalWrite()
 	s.sender.queueControlFrame(&wire.ResetStreamFrame{
 		StreamID:  s.streamID,
-		FinalSize: s.writeOffset,
+		FinalSize: finalSize,
 		ErrorCode: errorCode,
 	})
 	if newlyCompleted {
@@ -422,6 +485,24 @@ func (s *sendStream) Context() context.Context {
 	return s.ctx
 }
 
+func (s *sendStream) WaitUntilAcked(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.doneChan:
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.completed && s.cancelWriteErr == nil {
+		return nil
+	}
+	if s.cancelWriteErr != nil {
+		return s.cancelWriteErr
+	}
+	return s.closeForShutdownErr
+}
+
 func (s *sendStream) SetWriteDeadline(t time.Time) error {
 	s.mutex.Lock()
 	s.deadline = t
@@ -437,10 +518,37 @@ func (s *sendStream) closeForShutdown(err error) {
 	s.mutex.Lock()
 	s.ctxCancel(err)
 	s.closeForShutdownErr = err
+	s.signalDone()
+	released := s.releaseUnackedBytes()
 	s.mutex.Unlock()
+	if released > 0 {
+		s.sendBuffer.Release(released)
+	}
 	s.signalWrite()
 }
 
+// releaseUnackedBytes resets the number of unacknowledged bytes.
+// It returns the number of bytes that need to be released from the send buffer.
+// It must be called with the mutex held.
+func (s *sendStream) releaseUnackedBytes() protocol.ByteCount {
+	n := s.unackedBytes
+	s.unackedBytes = 0
+	if s.sendBuffer == nil {
+		return 0
+	}
+	return n
+}
+
+// signalDone closes the doneChan, if it wasn't closed already.
+// It must be called with the mutex held.
+func (s *sendStream) signalDone() {
+	select {
+	case <-s.doneChan:
+	default:
+		close(s.doneChan)
+	}
+}
+
 // signalWrite performs a non-blocking send on the writeChan
 func (s *sendStream) signalWrite() {
 	select {
@@ -455,6 +563,7 @@ var _ ackhandler.FrameHandler = &sendStreamAckHandler{}
 
 func (s *sendStreamAckHandler) OnAcked(f wire.Frame) {
 	sf := f.(*wire.StreamFrame)
+	dataLen := sf.DataLen()
 	sf.PutBack()
 	s.mutex.Lock()
 	if s.cancelWriteErr != nil {
@@ -466,8 +575,18 @@ func (s *sendStreamAckHandler) OnAcked(f wire.Frame) {
 		panic("numOutStandingFrames negative")
 	}
 	newlyCompleted := (*sendStream)(s).isNewlyCompleted()
+	var released protocol.ByteCount
+	if s.closeForShutdownErr == nil {
+		s.unackedBytes -= dataLen
+		if s.sendBuffer != nil {
+			released = dataLen
+		}
+	}
 	s.mutex.Unlock()
 
+	if released > 0 {
+		s.sendBuffer.Release(released)
+	}
 	if newlyCompleted {
 		s.sender.onStreamCompleted(s.streamID)
 	}
@@ -488,5 +607,5 @@ func (s *sendStreamAckHandler) OnLost(f wire.Frame) {
 	}
 	s.mutex.Unlock()
 
-	s.sender.onHasStreamData(s.streamID)
+	s.sender.onHasStreamRetransmission(s.streamID)
 }
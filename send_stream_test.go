@@ -12,8 +12,10 @@ import (
 	"golang.org/x/exp/rand"
 
 	"github.com/quic-go/quic-go/internal/ackhandler"
+	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/mocks"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -35,7 +37,7 @@ var _ = Describe("Send Stream", func() {
 	BeforeEach(func() {
 		mockSender = NewMockStreamSender(mockCtrl)
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newSendStream(streamID, mockSender, mockFC)
+		str = newSendStream(streamID, mockSender, mockFC, nil)
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = gbytes.TimeoutWriter(str, timeout)
@@ -323,6 +325,32 @@ var _ = Describe("Send Stream", func() {
 		})
 
 		Context("flow control blocking", func() {
+			It("buffers data written before the peer's flow control limits are known", func() {
+				// Before the handshake completes, we don't know the peer's flow control limits yet.
+				connFC := flowcontrol.NewConnectionFlowController(1000, 1000, nil, func(protocol.ByteCount) bool { return true }, &utils.RTTStats{}, utils.DefaultLogger)
+				fc := flowcontrol.NewStreamFlowController(streamID, connFC, 1000, 1000, 0, nil, &utils.RTTStats{}, utils.DefaultLogger)
+				str = newSendStream(streamID, mockSender, fc, nil)
+				mockSender.EXPECT().onHasStreamData(streamID)
+				n, err := str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(n).To(Equal(6))
+				_, ok, hasMoreData := str.popStreamFrame(1000, protocol.Version1)
+				Expect(ok).To(BeFalse())
+				Expect(hasMoreData).To(BeTrue())
+				Expect(connFC.SendWindowSize()).To(BeZero())
+
+				// the handshake completes, and we receive the peer's transport parameters
+				connFC.UpdateSendWindow(100)
+				mockSender.EXPECT().onHasStreamData(streamID)
+				str.updateSendWindow(100)
+				frame, ok, _ := str.popStreamFrame(1000, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				Expect(frame.Frame.Data).To(Equal([]byte("foobar")))
+				// flow control is accounted for when the data is sent
+				Expect(connFC.SendWindowSize()).To(Equal(protocol.ByteCount(94)))
+				Expect(fc.SendWindowSize()).To(Equal(protocol.ByteCount(94)))
+			})
+
 			It("queues a BLOCKED frame if the stream is flow control blocked", func() {
 				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(0))
 				mockFC.EXPECT().IsNewlyBlocked().Return(true, protocol.ByteCount(12))
@@ -383,6 +411,58 @@ var _ = Describe("Send Stream", func() {
 			})
 		})
 
+		Context("send buffer limit", func() {
+			It("blocks Write until the peer acknowledges data", func() {
+				unblocked := make(chan protocol.StreamID, 1)
+				limiter := newSendBufferLimiter(1000, func(id protocol.StreamID) { unblocked <- id })
+				str = newSendStream(streamID, mockSender, mockFC, limiter)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+				mockSender.EXPECT().onHasStreamData(streamID)
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					_, err := str.Write(getData(3000))
+					Expect(err).ToNot(HaveOccurred())
+				}()
+				waitForWrite()
+				frame, ok, hasMoreData := str.popStreamFrame(2000, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				Expect(frame.Frame.DataLen()).To(Equal(protocol.ByteCount(1000)))
+				Expect(hasMoreData).To(BeTrue())
+				_, ok, hasMoreData = str.popStreamFrame(2000, protocol.Version1)
+				Expect(ok).To(BeFalse())
+				Expect(hasMoreData).To(BeFalse())
+				Consistently(done).ShouldNot(BeClosed())
+
+				// acknowledging the data frees up space in the send buffer
+				frame.Handler.OnAcked(frame.Frame)
+				Eventually(unblocked).Should(Receive(Equal(streamID)))
+				frame, ok, _ = str.popStreamFrame(2000, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				Expect(frame.Frame.DataLen()).To(Equal(protocol.ByteCount(1000)))
+				Eventually(done).Should(BeClosed())
+			})
+
+			It("releases unacknowledged data when the stream is canceled", func() {
+				limiter := newSendBufferLimiter(1000, func(protocol.StreamID) {})
+				str = newSendStream(streamID, mockSender, mockFC, limiter)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount).AnyTimes()
+				mockFC.EXPECT().AddBytesSent(gomock.Any()).AnyTimes()
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, ok, _ := str.popStreamFrame(1000, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				Expect(limiter.Available(streamID)).To(Equal(protocol.ByteCount(994)))
+				mockSender.EXPECT().queueControlFrame(gomock.Any())
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				Expect(limiter.Available(streamID)).To(Equal(protocol.ByteCount(1000)))
+			})
+		})
+
 		Context("deadlines", func() {
 			It("returns an error when Write is called after the deadline", func() {
 				str.SetWriteDeadline(time.Now().Add(-time.Second))
@@ -715,6 +795,53 @@ var _ = Describe("Send Stream", func() {
 				str.CancelWrite(9876)
 			})
 
+			It("uses the number of bytes sent as the final size, when resetting in the middle of a Write", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.ByteCount(1000))
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(1000))
+				done := make(chan struct{})
+				go func() {
+					defer GinkgoRecover()
+					defer close(done)
+					n, err := str.Write(getData(5000))
+					Expect(err).To(MatchError(&StreamError{StreamID: streamID, ErrorCode: 1234, Remote: false}))
+					Expect(n).To(Equal(1000))
+				}()
+				waitForWrite()
+				frame, ok, _ := str.popStreamFrame(2000, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				Expect(frame.Frame.DataLen()).To(Equal(protocol.ByteCount(1000)))
+				gomock.InOrder(
+					mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+						StreamID:  streamID,
+						FinalSize: 1000,
+						ErrorCode: 1234,
+					}),
+					mockSender.EXPECT().onStreamCompleted(streamID),
+				)
+				str.CancelWrite(1234)
+				Eventually(done).Should(BeClosed())
+				// the buffered data is discarded
+				_, ok, hasMoreData := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+				Expect(ok).To(BeFalse())
+				Expect(hasMoreData).To(BeFalse())
+			})
+
+			It("discards data buffered in a STREAM frame when resetting", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				_, err := str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.nextFrame).ToNot(BeNil())
+				mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+					StreamID:  streamID,
+					FinalSize: 0,
+					ErrorCode: 1234,
+				})
+				mockSender.EXPECT().onStreamCompleted(streamID)
+				str.CancelWrite(1234)
+				Expect(str.nextFrame).To(BeNil())
+			})
+
 			// This test is inherently racy, as it tests a concurrent call to Write() and CancelRead().
 			// A single successful run of this test therefore doesn't mean a lot,
 			// for reliable results it has to be run many times.
@@ -935,6 +1062,38 @@ var _ = Describe("Send Stream", func() {
 					Remote:    true,
 				}))
 			})
+
+			It("resets the stream after data was sent, and fails subsequent Writes", func() {
+				mockSender.EXPECT().onHasStreamData(streamID)
+				mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+				mockFC.EXPECT().AddBytesSent(protocol.ByteCount(6))
+				_, err := str.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+				_, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+				Expect(ok).To(BeTrue())
+				gomock.InOrder(
+					mockSender.EXPECT().queueControlFrame(&wire.ResetStreamFrame{
+						StreamID:  streamID,
+						FinalSize: 6,
+						ErrorCode: 123,
+					}),
+					mockSender.EXPECT().onStreamCompleted(streamID),
+				)
+				str.handleStopSendingFrame(&wire.StopSendingFrame{
+					StreamID:  streamID,
+					ErrorCode: 123,
+				})
+				n, err := str.Write([]byte("raboof"))
+				Expect(n).To(BeZero())
+				var streamErr *StreamError
+				Expect(errors.As(err, &streamErr)).To(BeTrue())
+				Expect(streamErr.Remote).To(BeTrue())
+				Expect(streamErr.ErrorCode).To(BeEquivalentTo(123))
+				// the stream doesn't send any more data
+				_, ok, hasMoreData := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+				Expect(ok).To(BeFalse())
+				Expect(hasMoreData).To(BeFalse())
+			})
 		})
 	})
 
@@ -946,7 +1105,7 @@ var _ = Describe("Send Stream", func() {
 				Offset:         0x42,
 				DataLenPresent: false,
 			}
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamRetransmission(streamID)
 			(*sendStreamAckHandler)(str).OnLost(f)
 			frame, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
 			Expect(ok).To(BeTrue())
@@ -964,7 +1123,7 @@ var _ = Describe("Send Stream", func() {
 				Offset:         0x42,
 				DataLenPresent: false,
 			}
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamRetransmission(streamID)
 			(*sendStreamAckHandler)(str).OnLost(sf)
 			frame, ok, hasMoreData := str.popStreamFrame(sf.Length(protocol.Version1)-3, protocol.Version1)
 			Expect(ok).To(BeTrue())
@@ -983,6 +1142,33 @@ var _ = Describe("Send Stream", func() {
 			Expect(f.DataLenPresent).To(BeTrue())
 		})
 
+		It("pops retransmissions without popping new data", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			mockFC.EXPECT().SendWindowSize().Return(protocol.MaxByteCount)
+			mockFC.EXPECT().AddBytesSent(protocol.ByteCount(3))
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := strWithTimeout.Write([]byte("foo"))
+				Expect(err).ToNot(HaveOccurred())
+				close(done)
+			}()
+			waitForWrite()
+			frame, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			Eventually(done).Should(BeClosed())
+			_, ok, hasMore := str.popRetransmission(protocol.MaxByteCount, protocol.Version1)
+			Expect(ok).To(BeFalse())
+			Expect(hasMore).To(BeFalse())
+
+			mockSender.EXPECT().onHasStreamRetransmission(streamID)
+			frame.Handler.OnLost(frame.Frame)
+			ret, ok, hasMore := str.popRetransmission(protocol.MaxByteCount, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			Expect(hasMore).To(BeFalse())
+			Expect(ret.Frame.Data).To(Equal([]byte("foo")))
+		})
+
 		It("returns nil if the size is too small", func() {
 			str.numOutstandingFrames = 1
 			f := &wire.StreamFrame{
@@ -990,7 +1176,7 @@ var _ = Describe("Send Stream", func() {
 				Offset:         0x42,
 				DataLenPresent: false,
 			}
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamRetransmission(streamID)
 			(*sendStreamAckHandler)(str).OnLost(f)
 			_, ok, hasMoreData := str.popStreamFrame(2, protocol.Version1)
 			Expect(ok).To(BeFalse())
@@ -1016,7 +1202,7 @@ var _ = Describe("Send Stream", func() {
 			Expect(frame.Frame.Data).To(Equal([]byte("foobar")))
 
 			// now lose the frame
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamRetransmission(streamID)
 			frame.Handler.OnLost(frame.Frame)
 			newFrame, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
 			Expect(ok).To(BeTrue())
@@ -1045,7 +1231,7 @@ var _ = Describe("Send Stream", func() {
 				mockSender.EXPECT().onStreamCompleted(streamID),
 			)
 			str.CancelWrite(9876)
-			// don't EXPECT any calls to onHasStreamData
+			// don't EXPECT any calls to onHasStreamRetransmission
 			f.Handler.OnLost(f.Frame)
 			Expect(str.retransmissionQueue).To(BeEmpty())
 		})
@@ -1121,6 +1307,110 @@ var _ = Describe("Send Stream", func() {
 			frame.Handler.OnAcked(frame.Frame)
 		})
 
+		It("waits until all data, including the FIN, was acknowledged", func() {
+			mockSender.EXPECT().onHasStreamData(streamID).Times(2)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				_, err := strWithTimeout.Write(getData(100))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(str.Close()).To(Succeed())
+				close(done)
+			}()
+			waitForWrite()
+
+			var frames []ackhandler.StreamFrame
+			for {
+				frame, ok, _ := str.popStreamFrame(50, protocol.Version1)
+				if !ok {
+					continue
+				}
+				frames = append(frames, frame)
+				if frame.Frame.Fin {
+					break
+				}
+			}
+			Eventually(done).Should(BeClosed())
+
+			acked := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				acked <- str.WaitUntilAcked(context.Background())
+			}()
+			for _, f := range frames[:len(frames)-1] {
+				f.Handler.OnAcked(f.Frame)
+			}
+			Consistently(acked).ShouldNot(Receive())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			frames[len(frames)-1].Handler.OnAcked(frames[len(frames)-1].Frame)
+			Eventually(acked).Should(Receive(BeNil()))
+			// once acknowledged, WaitUntilAcked returns immediately
+			Expect(str.WaitUntilAcked(context.Background())).To(Succeed())
+		})
+
+		It("stops waiting for acknowledgements when the context is canceled", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			Expect(str.Close()).To(Succeed())
+			ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+			defer cancel()
+			Expect(str.WaitUntilAcked(ctx)).To(MatchError(context.DeadlineExceeded))
+		})
+
+		It("stops waiting for acknowledgements when the stream is canceled", func() {
+			acked := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				acked <- str.WaitUntilAcked(context.Background())
+			}()
+			Consistently(acked).ShouldNot(Receive())
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			var err error
+			Eventually(acked).Should(Receive(&err))
+			Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+		})
+
+		It("stops waiting for acknowledgements when the stream is canceled while data is in flight", func() {
+			mockSender.EXPECT().onHasStreamData(streamID)
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				_, err := strWithTimeout.Write([]byte("foobar"))
+				Expect(err).ToNot(HaveOccurred())
+			}()
+			waitForWrite()
+			_, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+			Expect(ok).To(BeTrue())
+			Eventually(done).Should(BeClosed())
+
+			acked := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				acked <- str.WaitUntilAcked(context.Background())
+			}()
+			Consistently(acked).ShouldNot(Receive())
+			mockSender.EXPECT().queueControlFrame(gomock.Any())
+			mockSender.EXPECT().onStreamCompleted(streamID)
+			str.CancelWrite(1234)
+			var err error
+			Eventually(acked).Should(Receive(&err))
+			Expect(err).To(Equal(&StreamError{StreamID: streamID, ErrorCode: 1234}))
+		})
+
+		It("stops waiting for acknowledgements when the connection is closed", func() {
+			acked := make(chan error, 1)
+			go func() {
+				defer GinkgoRecover()
+				acked <- str.WaitUntilAcked(context.Background())
+			}()
+			Consistently(acked).ShouldNot(Receive())
+			testErr := errors.New("test error")
+			str.closeForShutdown(testErr)
+			Eventually(acked).Should(Receive(MatchError(testErr)))
+		})
+
 		It("doesn't say it's completed when there are frames waiting to be retransmitted", func() {
 			mockSender.EXPECT().onHasStreamData(streamID)
 			done := make(chan struct{})
@@ -1152,7 +1442,7 @@ var _ = Describe("Send Stream", func() {
 			for _, f := range frames[1:] {
 				f.Handler.OnAcked(f.Frame)
 			}
-			mockSender.EXPECT().onHasStreamData(streamID)
+			mockSender.EXPECT().onHasStreamRetransmission(streamID)
 			frames[0].Handler.OnLost(frames[0].Frame)
 
 			// get the retransmission and acknowledge it
@@ -1170,6 +1460,7 @@ var _ = Describe("Send Stream", func() {
 		It("retransmits data until everything has been acknowledged", func() {
 			const dataLen = 1 << 22 // 4 MB
 			mockSender.EXPECT().onHasStreamData(streamID).AnyTimes()
+			mockSender.EXPECT().onHasStreamRetransmission(streamID).AnyTimes()
 			mockFC.EXPECT().SendWindowSize().DoAndReturn(func() protocol.ByteCount {
 				return protocol.ByteCount(mrand.Intn(500)) + 50
 			}).AnyTimes()
@@ -198,7 +198,7 @@ var _ = Describe("Server", func() {
 					Type:             protocol.PacketTypeInitial,
 					DestConnectionID: protocol.ParseConnectionID([]byte{1, 2, 3, 4}),
 					Version:          serv.config.Versions[0],
-				}, nil)
+				}, make([]byte, protocol.MinInitialPacketSize))
 				tracer.EXPECT().DroppedPacket(p.remoteAddr, logging.PacketTypeInitial, p.Size(), logging.PacketDropUnexpectedPacket)
 				serv.handlePacket(p)
 				// make sure there are no Write calls on the packet conn
@@ -422,7 +422,11 @@ var _ = Describe("Server", func() {
 
 			It("replies with a Retry packet, if a token is required", func() {
 				connID := protocol.ParseConnectionID([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
-				serv.config.RequireAddressValidation = func(net.Addr) bool { return true }
+				var validatedAddr net.Addr
+				serv.config.RequireAddressValidation = func(addr net.Addr) bool {
+					validatedAddr = addr
+					return true
+				}
 				hdr := &wire.Header{
 					Type:             protocol.PacketTypeInitial,
 					SrcConnectionID:  protocol.ParseConnectionID([]byte{5, 4, 3, 2, 1}),
@@ -452,6 +456,7 @@ var _ = Describe("Server", func() {
 				phm.EXPECT().Get(connID)
 				serv.handlePacket(packet)
 				Eventually(done).Should(BeClosed())
+				Expect(validatedAddr).To(Equal(raddr))
 			})
 
 			It("creates a connection, if no token is required", func() {
@@ -25,6 +25,8 @@ var errDeadline net.Error = &deadlineError{}
 type streamSender interface {
 	queueControlFrame(wire.Frame)
 	onHasStreamData(protocol.StreamID)
+	// called when a STREAM frame was lost and needs to be retransmitted
+	onHasStreamRetransmission(protocol.StreamID)
 	// must be called without holding the mutex that is acquired by closeForShutdown
 	onStreamCompleted(protocol.StreamID)
 }
@@ -44,6 +46,10 @@ func (s *uniStreamSender) onHasStreamData(id protocol.StreamID) {
 	s.streamSender.onHasStreamData(id)
 }
 
+func (s *uniStreamSender) onHasStreamRetransmission(id protocol.StreamID) {
+	s.streamSender.onHasStreamRetransmission(id)
+}
+
 func (s *uniStreamSender) onStreamCompleted(protocol.StreamID) {
 	s.onStreamCompletedImpl()
 }
@@ -61,6 +67,7 @@ type streamI interface {
 	hasData() bool
 	handleStopSendingFrame(*wire.StopSendingFrame)
 	popStreamFrame(maxBytes protocol.ByteCount, v protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool)
+	popRetransmission(maxBytes protocol.ByteCount, v protocol.VersionNumber) (ackhandler.StreamFrame, bool, bool)
 	updateSendWindow(protocol.ByteCount)
 }
 
@@ -76,6 +83,8 @@ type stream struct {
 	receiveStream
 	sendStream
 
+	perspective protocol.Perspective
+
 	completedMutex         sync.Mutex
 	sender                 streamSender
 	receiveStreamCompleted bool
@@ -86,10 +95,12 @@ var _ Stream = &stream{}
 
 // newStream creates a new Stream
 func newStream(streamID protocol.StreamID,
+	perspective protocol.Perspective,
 	sender streamSender,
 	flowController flowcontrol.StreamFlowController,
+	sendBuffer *sendBufferLimiter,
 ) *stream {
-	s := &stream{sender: sender}
+	s := &stream{sender: sender, perspective: perspective}
 	senderForSendStream := &uniStreamSender{
 		streamSender: sender,
 		onStreamCompletedImpl: func() {
@@ -99,7 +110,7 @@ func newStream(streamID protocol.StreamID,
 			s.completedMutex.Unlock()
 		},
 	}
-	s.sendStream = *newSendStream(streamID, senderForSendStream, flowController)
+	s.sendStream = *newSendStream(streamID, senderForSendStream, flowController, sendBuffer)
 	senderForReceiveStream := &uniStreamSender{
 		streamSender: sender,
 		onStreamCompletedImpl: func() {
@@ -119,6 +130,16 @@ func (s *stream) StreamID() protocol.StreamID {
 	return s.sendStream.StreamID()
 }
 
+// need to define flowControlState() here, since both receiveStream and sendStream have a flowControlState()
+func (s *stream) flowControlState() flowcontrol.State {
+	// receiveStream and sendStream share the same flow controller
+	return s.sendStream.flowControlState()
+}
+
+func (s *stream) IsLocal() bool {
+	return s.StreamID().InitiatedBy() == s.perspective
+}
+
 func (s *stream) Close() error {
 	return s.sendStream.Close()
 }
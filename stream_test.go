@@ -7,8 +7,10 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/mocks"
 	"github.com/quic-go/quic-go/internal/protocol"
+	"github.com/quic-go/quic-go/internal/utils"
 	"github.com/quic-go/quic-go/internal/wire"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -41,7 +43,7 @@ var _ = Describe("Stream", func() {
 	BeforeEach(func() {
 		mockSender = NewMockStreamSender(mockCtrl)
 		mockFC = mocks.NewMockStreamFlowController(mockCtrl)
-		str = newStream(streamID, mockSender, mockFC)
+		str = newStream(streamID, protocol.PerspectiveServer, mockSender, mockFC, nil)
 
 		timeout := scaleDuration(250 * time.Millisecond)
 		strWithTimeout = struct {
@@ -53,10 +55,71 @@ var _ = Describe("Stream", func() {
 		}
 	})
 
+	It("says if the stream was opened locally", func() {
+		Expect(str.IsLocal()).To(BeTrue()) // stream 1337 is a server-initiated stream
+		str = newStream(streamID, protocol.PerspectiveClient, mockSender, mockFC, nil)
+		Expect(str.IsLocal()).To(BeFalse())
+	})
+
 	It("gets stream id", func() {
 		Expect(str.StreamID()).To(Equal(protocol.StreamID(1337)))
 	})
 
+	It("reports the flow control state", func() {
+		rttStats := &utils.RTTStats{}
+		connFC := flowcontrol.NewConnectionFlowController(1000, 1000, nil, nil, rttStats, utils.DefaultLogger)
+		connFC.UpdateSendWindow(1000)
+		fc := flowcontrol.NewStreamFlowController(streamID, connFC, 100, 100, 50, func(protocol.StreamID) {}, rttStats, utils.DefaultLogger)
+		str = newStream(streamID, protocol.PerspectiveServer, mockSender, fc, nil)
+
+		// send 30 bytes
+		mockSender.EXPECT().onHasStreamData(streamID)
+		_, err := str.Write(make([]byte, 30))
+		Expect(err).ToNot(HaveOccurred())
+		frame, ok, _ := str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+		Expect(ok).To(BeTrue())
+		Expect(frame.Frame.DataLen()).To(Equal(protocol.ByteCount(30)))
+		Expect(str.flowControlState()).To(Equal(flowcontrol.State{
+			BytesSent:     30,
+			SendWindow:    50,
+			ReceiveWindow: 100,
+		}))
+
+		// receive 40 bytes, and read 10 of them
+		Expect(str.handleStreamFrame(&wire.StreamFrame{StreamID: streamID, Data: make([]byte, 40)})).To(Succeed())
+		_, err = io.ReadFull(str, make([]byte, 10))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(str.flowControlState()).To(Equal(flowcontrol.State{
+			BytesSent:       30,
+			SendWindow:      50,
+			BytesRead:       10,
+			HighestReceived: 40,
+			ReceiveWindow:   100,
+		}))
+
+		// send another 30 bytes, only 20 of which are allowed by flow control
+		mockSender.EXPECT().onHasStreamData(streamID)
+		_, err = str.Write(make([]byte, 30))
+		Expect(err).ToNot(HaveOccurred())
+		frame, ok, _ = str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+		Expect(ok).To(BeTrue())
+		Expect(frame.Frame.DataLen()).To(Equal(protocol.ByteCount(20)))
+		mockSender.EXPECT().queueControlFrame(&wire.StreamDataBlockedFrame{StreamID: streamID, MaximumStreamData: 50})
+		_, ok, _ = str.popStreamFrame(protocol.MaxByteCount, protocol.Version1)
+		Expect(ok).To(BeFalse())
+		state := str.flowControlState()
+		Expect(state.BytesSent).To(Equal(protocol.ByteCount(50)))
+		Expect(state.SendWindow).To(Equal(protocol.ByteCount(50)))
+		Expect(state.Blocked).To(BeTrue())
+
+		// the peer increases the flow control limit
+		mockSender.EXPECT().onHasStreamData(streamID) // 10 bytes are still waiting to be sent
+		str.updateSendWindow(100)
+		state = str.flowControlState()
+		Expect(state.SendWindow).To(Equal(protocol.ByteCount(100)))
+		Expect(state.Blocked).To(BeFalse())
+	})
+
 	Context("deadlines", func() {
 		It("sets a write deadline, when SetDeadline is called", func() {
 			str.SetDeadline(time.Now().Add(-time.Second))
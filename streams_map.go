@@ -52,6 +52,7 @@ type streamsMap struct {
 
 	sender            streamSender
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController
+	sendBuffer        *sendBufferLimiter
 
 	mutex               sync.Mutex
 	outgoingBidiStreams *outgoingStreamsMap[streamI]
@@ -66,6 +67,7 @@ var _ streamManager = &streamsMap{}
 func newStreamsMap(
 	sender streamSender,
 	newFlowController func(protocol.StreamID) flowcontrol.StreamFlowController,
+	sendBuffer *sendBufferLimiter,
 	maxIncomingBidiStreams uint64,
 	maxIncomingUniStreams uint64,
 	perspective protocol.Perspective,
@@ -73,6 +75,7 @@ func newStreamsMap(
 	m := &streamsMap{
 		perspective:            perspective,
 		newFlowController:      newFlowController,
+		sendBuffer:             sendBuffer,
 		maxIncomingBidiStreams: maxIncomingBidiStreams,
 		maxIncomingUniStreams:  maxIncomingUniStreams,
 		sender:                 sender,
@@ -86,7 +89,7 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeBidi,
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, m.perspective)
-			return newStream(id, m.sender, m.newFlowController(id))
+			return newStream(id, m.perspective, m.sender, m.newFlowController(id), m.sendBuffer)
 		},
 		m.sender.queueControlFrame,
 	)
@@ -94,7 +97,7 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeBidi,
 		func(num protocol.StreamNum) streamI {
 			id := num.StreamID(protocol.StreamTypeBidi, m.perspective.Opposite())
-			return newStream(id, m.sender, m.newFlowController(id))
+			return newStream(id, m.perspective, m.sender, m.newFlowController(id), m.sendBuffer)
 		},
 		m.maxIncomingBidiStreams,
 		m.sender.queueControlFrame,
@@ -103,7 +106,7 @@ func (m *streamsMap) initMaps() {
 		protocol.StreamTypeUni,
 		func(num protocol.StreamNum) sendStreamI {
 			id := num.StreamID(protocol.StreamTypeUni, m.perspective)
-			return newSendStream(id, m.sender, m.newFlowController(id))
+			return newSendStream(id, m.sender, m.newFlowController(id), m.sendBuffer)
 		},
 		m.sender.queueControlFrame,
 	)
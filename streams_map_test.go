@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/quic-go/quic-go/internal/flowcontrol"
 	"github.com/quic-go/quic-go/internal/mocks"
@@ -87,7 +88,7 @@ var _ = Describe("Streams Map", func() {
 
 			BeforeEach(func() {
 				mockSender = NewMockStreamSender(mockCtrl)
-				m = newStreamsMap(mockSender, newFlowController, MaxBidiStreamNum, MaxUniStreamNum, perspective).(*streamsMap)
+				m = newStreamsMap(mockSender, newFlowController, nil, MaxBidiStreamNum, MaxUniStreamNum, perspective).(*streamsMap)
 			})
 
 			Context("opening", func() {
@@ -97,12 +98,27 @@ var _ = Describe("Streams Map", func() {
 					Expect(err).ToNot(HaveOccurred())
 					Expect(str).To(BeAssignableToTypeOf(&stream{}))
 					Expect(str.StreamID()).To(Equal(ids.firstOutgoingBidiStream))
+					Expect(str.IsLocal()).To(BeTrue())
 					str, err = m.OpenStream()
 					Expect(err).ToNot(HaveOccurred())
 					Expect(str).To(BeAssignableToTypeOf(&stream{}))
 					Expect(str.StreamID()).To(Equal(ids.firstOutgoingBidiStream + 4))
 				})
 
+				It("sends a STREAMS_BLOCKED frame when the peer doesn't allow opening more streams", func() {
+					m.UpdateLimits(&wire.TransportParameters{MaxBidiStreamNum: 1})
+					_, err := m.OpenStream()
+					Expect(err).ToNot(HaveOccurred())
+					mockSender.EXPECT().queueControlFrame(&wire.StreamsBlockedFrame{
+						Type:        protocol.StreamTypeBidi,
+						StreamLimit: 1,
+					})
+					ctx, cancel := context.WithTimeout(context.Background(), scaleDuration(20*time.Millisecond))
+					defer cancel()
+					_, err = m.OpenStreamSync(ctx)
+					Expect(err).To(MatchError(context.DeadlineExceeded))
+				})
+
 				It("opens unidirectional streams", func() {
 					allowUnlimitedStreams()
 					str, err := m.OpenUniStream()
@@ -124,6 +140,7 @@ var _ = Describe("Streams Map", func() {
 					Expect(err).ToNot(HaveOccurred())
 					Expect(str).To(BeAssignableToTypeOf(&stream{}))
 					Expect(str.StreamID()).To(Equal(ids.firstIncomingBidiStream))
+					Expect(str.IsLocal()).To(BeFalse())
 				})
 
 				It("accepts unidirectional streams", func() {
@@ -48,7 +48,11 @@ func (q *windowUpdateQueue) QueueAll() {
 	q.mutex.Lock()
 	// queue a connection-level window update
 	if q.queuedConn {
-		q.callback(&wire.MaxDataFrame{MaximumData: q.connFlowController.GetWindowUpdate()})
+		// the offset can be 0 if we queued the window update in response to a DATA_BLOCKED frame,
+		// but the flow controller doesn't consider the window update necessary yet
+		if offset := q.connFlowController.GetWindowUpdate(); offset > 0 {
+			q.callback(&wire.MaxDataFrame{MaximumData: offset})
+		}
 		q.queuedConn = false
 	}
 	// queue all stream-level window updates
@@ -98,6 +98,13 @@ var _ = Describe("Window Update Queue", func() {
 		}))
 	})
 
+	It("doesn't queue a MAX_DATA frame if the flow controller returns an offset of 0", func() {
+		connFC.EXPECT().GetWindowUpdate().Return(protocol.ByteCount(0))
+		q.AddConnection()
+		q.QueueAll()
+		Expect(queuedFrames).To(BeEmpty())
+	})
+
 	It("deduplicates", func() {
 		stream10 := NewMockStreamI(mockCtrl)
 		stream10.EXPECT().getWindowUpdate().Return(protocol.ByteCount(200))